@@ -0,0 +1,202 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"gmod-cef-codec-fix-native/internal/diagnostics_util"
+	"gmod-cef-codec-fix-native/internal/log_util"
+	"gmod-cef-codec-fix-native/internal/patching_util"
+	"gmod-cef-codec-fix-native/internal/steam_util"
+)
+
+// Everything about the machine and the tool itself that goes into system.json
+type diagnosticsSystem struct {
+	ToolVersion     string
+	GoVersion       string
+	OS              string
+	Arch            string
+	OSDescription   string
+	ManifestSHA256  string
+	ManifestUpdated time.Time
+	CompatTool      string
+	Redacted        bool
+	Errors          []string
+}
+
+// The parsed Steam files we used, which goes into steam.json
+type diagnosticsSteam struct {
+	Environment *gmodEnvironment
+	Libraries   *steam_util.VdfLibraryFolders
+	LoginUsers  *steam_util.VdfLoginUsers
+}
+
+func diagnosticsFileName() string {
+	return fmt.Sprintf("gmodcefcodecfix-diagnostics-%v.zip", time.Now().Format("20060102-150405"))
+}
+
+// Collect everything we'd ask for in a support request into one zip.
+// Nothing here is fatal, whatever couldn't be collected is listed in system.json instead.
+func writeDiagnostics(w io.Writer, logRing *log_util.RingBuffer, redact bool) error {
+	system := diagnosticsSystem{
+		ToolVersion:   toolVersion(),
+		GoVersion:     runtime.Version(),
+		OS:            runtime.GOOS,
+		Arch:          runtime.GOARCH,
+		OSDescription: osDescription(),
+		Redacted:      redact,
+	}
+	addError := func(what string, err error) {
+		slog.Warn("Diagnostics are incomplete", "what", what, "err", err)
+		system.Errors = append(system.Errors, fmt.Sprintf("%v: %v", what, err))
+	}
+
+	var steam diagnosticsSteam
+	var report *StatusReport
+	env, err := detectEnvironment()
+	if err != nil {
+		addError("detecting GMod", err)
+	} else {
+		steam.Environment = env
+		if steam.Libraries, err = steam_util.GetSteamLibraries(env.SteamPath, env.SteamPackaging); err != nil {
+			addError("reading libraryfolders.vdf", err)
+		}
+		if steam.LoginUsers, err = steam_util.GetLoginUsers(env.SteamPath); err != nil {
+			addError("reading loginusers.vdf", err)
+		}
+		system.CompatTool = env.CompatTool.String()
+		report, err = diagnosticsReport(env)
+		if err != nil {
+			addError("checking files", err)
+		}
+	}
+	if cacheDir, err := patching_util.GetCacheDir(); err == nil {
+		system.ManifestSHA256, system.ManifestUpdated, err = patching_util.CachedManifestVersion(cacheDir)
+		if err != nil {
+			addError("reading the cached manifest", err)
+		}
+	}
+
+	var redactor *diagnostics_util.Redactor
+	if redact {
+		// Not from steam.LoginUsers, a failed detection is the usual reason for sending diagnostics
+		redactor, err = diagnostics_util.NewSteamRedactor()
+		if err != nil {
+			addError("reading loginusers.vdf for redaction", err)
+		}
+	}
+
+	zipWriter := zip.NewWriter(w)
+	for _, jsonFile := range []struct {
+		name  string
+		value any
+	}{
+		{"status.json", report},
+		{"steam.json", steam},
+		{"system.json", system},
+	} {
+		encoded, err := redactor.MarshalIndent(jsonFile.value)
+		if err != nil {
+			return err
+		}
+		if err := writeZipFile(zipWriter, jsonFile.name, encoded); err != nil {
+			return err
+		}
+	}
+	for name, contents := range diagnosticsLogs(logRing) {
+		if err := writeZipFile(zipWriter, "logs/"+name, redactor.RedactLog(contents)); err != nil {
+			return err
+		}
+	}
+	return zipWriter.Close()
+}
+
+// Same check as a normal run but without patching anything
+func diagnosticsReport(env *gmodEnvironment) (*StatusReport, error) {
+	cacheDir, err := patching_util.GetCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	patchManifest, err := patching_util.LoadManifest(context.Background(), cacheDir, WATCH_MANIFEST_MAX_AGE)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := patchManifest.GetBranch(env.TargetPlatform, env.Branch)
+	if err != nil {
+		return nil, err
+	}
+	return checkFiles(context.Background(), env, manifest, patching_util.LoadHashCache(cacheDir), false, nil), nil
+}
+
+// The log file and the one before it, or just this session if there is no log file
+func diagnosticsLogs(logRing *log_util.RingBuffer) map[string][]byte {
+	logs := make(map[string][]byte)
+	if logDir, err := log_util.GetLogDir(); err == nil {
+		for _, name := range []string{"GModCEFCodecFix.log", "GModCEFCodecFix.log.1"} {
+			if contents, err := os.ReadFile(filepath.Join(logDir, name)); err == nil {
+				logs[name] = contents
+			}
+		}
+	}
+	if len(logs) == 0 && logRing != nil {
+		var session strings.Builder
+		for _, entry := range logRing.Entries() {
+			fmt.Fprintln(&session, entry)
+		}
+		logs["session.log"] = []byte(session.String())
+	}
+	return logs
+}
+
+func writeZipFile(zipWriter *zip.Writer, name string, contents []byte) error {
+	fileWriter, err := zipWriter.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = fileWriter.Write(contents)
+	return err
+}
+
+func toolVersion() string {
+	buildInfo, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	version := buildInfo.Main.Version
+	for _, setting := range buildInfo.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			version += " " + setting.Value
+		case "vcs.modified":
+			if setting.Value == "true" {
+				version += " (modified)"
+			}
+		}
+	}
+	return version
+}
+
+func saveDiagnostics(path string, logRing *log_util.RingBuffer, redact bool) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writeDiagnostics(file, logRing, redact); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	slog.Info("Saved diagnostics", "path", path, "redacted", redact)
+	return nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+
+	"gmod-cef-codec-fix-native/internal/patching_util"
+	"gmod-cef-codec-fix-native/internal/steam_steamid"
+	"gmod-cef-codec-fix-native/internal/steam_util"
+	"gmod-cef-codec-fix-native/internal/ui"
+)
+
+// Re-detect everything and show it in the header
+func refreshEnvironmentPanel(panel *ui.EnvironmentPanel) {
+	env, err := detectEnvironment()
+	if err != nil {
+		warning := "Couldn't detect GMod"
+		var unavailableErr *steam_util.LibraryUnavailableError
+		if errors.As(err, &unavailableErr) {
+			warning = "GMod's library isn't available"
+		}
+		panel.SetAvatar("")
+		panel.SetFields([]ui.EnvironmentField{{Label: "Error", Value: err.Error(), Warning: warning}})
+		return
+	}
+	var userNames []string
+	selectedUser := 0
+	for i, steamUser := range env.SteamUsers {
+		userName := fmt.Sprintf("%v (%v)", steamUser.PersonaName, steamUser.AccountName)
+		if !steamUser.HasLocalConfig {
+			userName += " - no localconfig.vdf"
+		}
+		userNames = append(userNames, userName)
+		if steamUser.SteamID64 == env.SteamUser.SteamID64 {
+			selectedUser = i
+			panel.SetAvatar(steamUser.AvatarPath)
+		}
+	}
+	panel.SetUsers(userNames, selectedUser, func(i int) {
+		settings.SetUser(fmt.Sprintf("%v", env.SteamUsers[i].SteamID64))
+		go refreshEnvironmentPanel(panel)
+	})
+	panel.SetFields(environmentFields(env))
+}
+
+func environmentFields(env *gmodEnvironment) []ui.EnvironmentField {
+	userField := ui.EnvironmentField{
+		Label: "User",
+		Value: fmt.Sprintf("%v (%v)", env.SteamUser.PersonaName, env.SteamUser.AccountName),
+	}
+	if env.SteamUser.MostRecent != 1 {
+		userField.Warning = "Not the most recent login"
+	}
+
+	steamIdField := ui.EnvironmentField{Label: "SteamID"}
+	steamId, err := steam_steamid.ParseSteamID(fmt.Sprintf("%v", env.SteamUser.SteamID64))
+	if err != nil {
+		steamIdField.Value = fmt.Sprintf("%v", env.SteamUser.SteamID64)
+		steamIdField.Warning = "Invalid SteamID"
+	} else {
+		steamIdField.Value = fmt.Sprintf("%v %v", steamId.Steam3(), env.SteamUser.SteamID64)
+		if profileURL, err := steamId.ProfileURL(); err == nil {
+			steamIdField.Value += " " + profileURL
+		}
+		// loginusers.vdf should only ever have normal accounts, anything else means it's damaged
+		if steamId.Type != steam_steamid.TypeIndividual || steamId.Universe != steam_steamid.UniversePublic {
+			steamIdField.Warning = "Not a normal account: " + steamId.Describe()
+		}
+	}
+
+	steamField := ui.EnvironmentField{Label: "Steam", Value: env.SteamPath}
+	if env.SteamPackaging.IsSandboxed() {
+		steamField.Value += fmt.Sprintf(" (%v)", env.SteamPackaging.Type)
+		if len(env.LibraryWarnings) > 0 {
+			steamField.Warning = fmt.Sprintf("%v libraries aren't accessible", len(env.LibraryWarnings))
+		}
+	}
+
+	libraryField := ui.EnvironmentField{Label: "Library", Value: env.Install.LibraryPath}
+	if warning := env.LibraryWarnings[env.Install.LibraryPath]; warning != "" {
+		libraryField.Warning = "Not accessible to " + env.SteamPackaging.Type
+	} else if len(env.Installs) > 1 {
+		libraryField.Warning = fmt.Sprintf("GMod is in %v libraries", len(env.Installs))
+	}
+
+	appState := env.Install.Manifest.AppState
+	buildField := ui.EnvironmentField{Label: "GMod", Value: fmt.Sprintf("build %v on branch %v", appState.BuildID, env.Branch)}
+	if download := env.Install.DownloadProgress(); download.InProgress() {
+		buildField.Warning = "Updating: " + describeDownload(download)
+	} else if !steam_util.GameIsInGoodState(env.Install.Manifest) {
+		buildField.Warning = fmt.Sprintf("Not ready (%v)", appState.StateFlags)
+	} else if appState.TargetBuildID != 0 && appState.BuildID != appState.TargetBuildID {
+		buildField.Warning = fmt.Sprintf("Update to build %v pending", appState.TargetBuildID)
+	} else if cacheDir, err := patching_util.GetCacheDir(); err == nil {
+		if patchManifest, err := patching_util.LoadManifest(context.Background(), cacheDir, WATCH_MANIFEST_MAX_AGE); err == nil &&
+			!slices.Contains(patchManifest.Branches(env.TargetPlatform), env.Branch) {
+			buildField.Warning = "No patch for this branch"
+		}
+	}
+
+	platformField := ui.EnvironmentField{Label: "Platform", Value: env.TargetPlatform + " (native)"}
+	if env.UsingProton {
+		platformField.Value = fmt.Sprintf("%v (%v)", env.TargetPlatform, env.CompatTool)
+	} else if env.CompatTool != nil {
+		platformField.Value = fmt.Sprintf("%v (native, %v)", env.TargetPlatform, env.CompatTool)
+	} else if runtime.GOOS == "linux" && env.TargetPlatform != "linux" {
+		platformField.Warning = "Unexpected platform"
+	}
+
+	fields := []ui.EnvironmentField{
+		userField,
+		steamIdField,
+		steamField,
+		libraryField,
+		buildField,
+		platformField,
+	}
+	if env.ProtonPrefix != nil {
+		prefixField := ui.EnvironmentField{Label: "Prefix", Value: env.ProtonPrefix.CompatDataPath}
+		if env.ProtonPrefix.CreatedWith != "" {
+			prefixField.Value += " (" + env.ProtonPrefix.CreatedWith + ")"
+		}
+		if health, _ := env.ProtonPrefix.Health(env.CompatTool); health != steam_util.PREFIX_HEALTH_OK {
+			prefixField.Warning = health
+		}
+		fields = append(fields, prefixField)
+	}
+	return fields
+}
@@ -0,0 +1,24 @@
+//go:build !windows
+
+package main
+
+import (
+	"log/slog"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// Replace ourselves with the game, which keeps its argv and environment intact
+// and means Steam sees its exit code directly
+func execGame(gameCommand []string) int {
+	gamePath, err := exec.LookPath(gameCommand[0])
+	if err != nil {
+		slog.Error("Couldn't find the game", "err", err)
+		return 1
+	}
+	err = syscall.Exec(gamePath, gameCommand, os.Environ())
+	// Exec only returns if it failed
+	slog.Error("Couldn't start the game", "err", err)
+	return 1
+}
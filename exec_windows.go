@@ -0,0 +1,25 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"os/exec"
+)
+
+// Windows can't replace the running process, so run the game and pass its exit code along
+func execGame(gameCommand []string) int {
+	cmd := exec.Command(gameCommand[0], gameCommand[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		slog.Error("Couldn't start the game", "err", err)
+		return 1
+	}
+	return 0
+}
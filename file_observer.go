@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+
+	"gmod-cef-codec-fix-native/internal/ui"
+)
+
+// Lets something like the GUI follow along while checkFiles runs, methods get called from multiple goroutines
+type fileObserver interface {
+	FilesStarted(files []FileStatus)
+	FileProgress(path string, stage string, done int64, total int64)
+	FileFinished(file FileStatus)
+	Finished(report *StatusReport)
+}
+
+type nopFileObserver struct{}
+
+func (nopFileObserver) FilesStarted(files []FileStatus)                                 {}
+func (nopFileObserver) FileProgress(path string, stage string, done int64, total int64) {}
+func (nopFileObserver) FileFinished(file FileStatus)                                    {}
+func (nopFileObserver) Finished(report *StatusReport)                                   {}
+
+type resultsTableObserver struct {
+	table *ui.ResultsTable
+}
+
+func (o *resultsTableObserver) FilesStarted(files []FileStatus) {
+	paths := make([]string, len(files))
+	expected := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = file.Path
+		expected[i] = file.Expected
+	}
+	o.table.Reset(paths, expected)
+}
+
+func (o *resultsTableObserver) FileProgress(path string, stage string, done int64, total int64) {
+	progress := 0.0
+	if total > 0 {
+		progress = float64(done) / float64(total)
+	}
+	o.table.SetProgress(path, stage, progress)
+}
+
+func (o *resultsTableObserver) FileFinished(file FileStatus) {
+	o.table.UpdateFile(ui.FileResult{
+		Path:     file.Path,
+		Status:   file.Status,
+		Expected: file.Expected,
+		Actual:   file.Actual,
+		Size:     formatBytes(file.Size),
+		Action:   file.Action,
+		Stage:    "done",
+		Progress: 1,
+	})
+}
+
+func (o *resultsTableObserver) Finished(report *StatusReport) {
+	patched := 0
+	failed := 0
+	for _, file := range report.Files {
+		switch file.Status {
+		case FILE_STATUS_PATCHED:
+			patched++
+		case FILE_STATUS_OK:
+		default:
+			failed++
+		}
+	}
+	if failed > 0 {
+		o.table.SetSummary(fmt.Sprintf("%v of %v files aren't patched", failed, len(report.Files)), false)
+	} else if patched > 0 {
+		o.table.SetSummary(fmt.Sprintf("Patched %v of %v files, GMod build %v is ready", patched, len(report.Files), report.BuildID), true)
+	} else {
+		o.table.SetSummary(fmt.Sprintf("All %v files were already patched, GMod build %v is ready", len(report.Files), report.BuildID), true)
+	}
+}
@@ -0,0 +1,257 @@
+package diagnostics_util
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gmod-cef-codec-fix-native/internal/steam_util"
+)
+
+// Hides who the user is from the bundle: account names, persona names, SteamIDs and the home directory.
+// Every account gets its own number so support can still tell them apart. A nil Redactor leaves everything alone.
+type Redactor struct {
+	// Longest first, so a name that contains another one wins
+	secrets []redactedSecret
+	// The secrets again in every way a log line can have them escaped
+	logSecrets []redactedSecret
+	// SteamIDs can also show up as JSON numbers
+	idLabels map[string]string
+}
+
+type redactedSecret struct {
+	value string
+	label string
+}
+
+func NewRedactor(loginUsers *steam_util.VdfLoginUsers) *Redactor {
+	var secrets []redactedSecret
+	if homeDir, err := os.UserHomeDir(); err == nil && homeDir != "" {
+		secrets = append(secrets, redactedSecret{homeDir, "~"})
+		if slashHomeDir := filepath.ToSlash(homeDir); slashHomeDir != homeDir {
+			secrets = append(secrets, redactedSecret{slashHomeDir, "~"})
+		}
+	}
+	idLabels := make(map[string]string)
+	if loginUsers != nil {
+		// Sorted so the numbers don't depend on map order
+		steamIds := make([]uint64, 0, len(loginUsers.Users))
+		for steamId64 := range loginUsers.Users {
+			steamIds = append(steamIds, steamId64)
+		}
+		slices.Sort(steamIds)
+		for i, steamId64 := range steamIds {
+			steamUser := loginUsers.Users[steamId64]
+			if steamUser.AccountName != "" {
+				secrets = append(secrets, redactedSecret{steamUser.AccountName, fmt.Sprintf("<account name %v>", i+1)})
+			}
+			if steamUser.PersonaName != "" {
+				secrets = append(secrets, redactedSecret{steamUser.PersonaName, fmt.Sprintf("<persona name %v>", i+1)})
+			}
+			idLabels[fmt.Sprintf("%v", steamId64)] = fmt.Sprintf("<steamid64 %v>", i+1)
+			idLabels[fmt.Sprintf("%v", steamId64&0xFFFFFFFF)] = fmt.Sprintf("<account id %v>", i+1)
+		}
+	}
+	for id, label := range idLabels {
+		secrets = append(secrets, redactedSecret{id, label})
+	}
+	var logSecrets []redactedSecret
+	for _, secret := range secrets {
+		for _, form := range escapedForms(secret.value) {
+			logSecrets = append(logSecrets, redactedSecret{form, secret.label})
+		}
+	}
+	return &Redactor{secrets: sortSecrets(secrets), logSecrets: sortSecrets(logSecrets), idLabels: idLabels}
+}
+
+func sortSecrets(secrets []redactedSecret) []redactedSecret {
+	sort.SliceStable(secrets, func(i, j int) bool {
+		if len(secrets[i].value) != len(secrets[j].value) {
+			return len(secrets[i].value) > len(secrets[j].value)
+		}
+		return secrets[i].value < secrets[j].value
+	})
+	return secrets
+}
+
+// NewSteamRedactor reads loginusers.vdf by itself, so names and IDs are hidden even when detecting GMod failed.
+// If that doesn't work the returned Redactor still hides the home directory.
+func NewSteamRedactor() (*Redactor, error) {
+	steamPath, err := steam_util.GetSteamPath()
+	if err != nil {
+		return NewRedactor(nil), err
+	}
+	loginUsers, err := steam_util.GetLoginUsers(steamPath)
+	if err != nil {
+		return NewRedactor(nil), err
+	}
+	return NewRedactor(loginUsers), nil
+}
+
+// MarshalIndent encodes value like json.MarshalIndent with the secrets taken out of every string, object key
+// and number that is exactly a SteamID. It works on the values and not the encoded text, so escaping
+// can't hide a name and a short name can't break the JSON.
+func (r *Redactor) MarshalIndent(value any) ([]byte, error) {
+	if r == nil {
+		return json.MarshalIndent(value, "", "  ")
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var redacted bytes.Buffer
+	// For every open object or array: whether it's an object, and whether the next string in it is a key
+	type level struct {
+		object    bool
+		expectKey bool
+		empty     bool
+	}
+	var levels []*level
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var parent *level
+		if len(levels) > 0 {
+			parent = levels[len(levels)-1]
+		}
+		if delim, ok := token.(json.Delim); ok && (delim == '}' || delim == ']') {
+			redacted.WriteRune(rune(delim))
+			levels = levels[:len(levels)-1]
+			if len(levels) > 0 && levels[len(levels)-1].object {
+				levels[len(levels)-1].expectKey = true
+			}
+			continue
+		}
+		isKey := parent != nil && parent.object && parent.expectKey
+		if parent != nil {
+			if !parent.empty && (isKey || !parent.object) {
+				redacted.WriteByte(',')
+			}
+			parent.empty = false
+		}
+		switch value := token.(type) {
+		case json.Delim:
+			redacted.WriteRune(rune(value))
+			levels = append(levels, &level{object: value == '{', expectKey: value == '{', empty: true})
+			continue
+		case string:
+			quoted, err := json.Marshal(r.redactString(value))
+			if err != nil {
+				return nil, err
+			}
+			redacted.Write(quoted)
+		case json.Number:
+			if label, found := r.idLabels[value.String()]; found {
+				quoted, _ := json.Marshal(label)
+				redacted.Write(quoted)
+			} else {
+				redacted.WriteString(value.String())
+			}
+		case bool:
+			redacted.WriteString(strconv.FormatBool(value))
+		case nil:
+			redacted.WriteString("null")
+		}
+		if isKey {
+			redacted.WriteByte(':')
+			parent.expectKey = false
+		} else if parent != nil && parent.object {
+			parent.expectKey = true
+		}
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, redacted.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	return indented.Bytes(), nil
+}
+
+func (r *Redactor) redactString(value string) string {
+	return replaceTokens(value, r.secrets)
+}
+
+// RedactLog hides the secrets in log text, both as they are and the way slog's text handler
+// and JSON escape them. Only whole words are replaced, so a persona name like "4" leaves 4000 alone.
+func (r *Redactor) RedactLog(contents []byte) []byte {
+	if r == nil {
+		return contents
+	}
+	return []byte(replaceTokens(string(contents), r.logSecrets))
+}
+
+// The value itself, quoted Go style like slog does and JSON escaped, without the surrounding quotes
+func escapedForms(value string) []string {
+	forms := []string{value}
+	goQuoted := strconv.Quote(value)
+	jsonQuoted, _ := json.Marshal(value)
+	for _, quoted := range []string{goQuoted, string(jsonQuoted)} {
+		if form := quoted[1 : len(quoted)-1]; !slices.Contains(forms, form) {
+			forms = append(forms, form)
+		}
+	}
+	return forms
+}
+
+// Replace the secrets in one pass, so a label that was just put in can't be matched again.
+// A secret only counts where it isn't part of a longer word: if it starts or ends with a letter
+// or digit, the text around it mustn't continue with one.
+func replaceTokens(text string, secrets []redactedSecret) string {
+	var firstBytes [256]bool
+	for _, secret := range secrets {
+		if secret.value != "" {
+			firstBytes[secret.value[0]] = true
+		}
+	}
+	var result strings.Builder
+	copied := 0
+	for i := 0; i < len(text); i++ {
+		if !firstBytes[text[i]] {
+			continue
+		}
+		for _, secret := range secrets {
+			if secret.value == "" || !strings.HasPrefix(text[i:], secret.value) || !isToken(text, i, i+len(secret.value)) {
+				continue
+			}
+			result.WriteString(text[copied:i])
+			result.WriteString(secret.label)
+			copied = i + len(secret.value)
+			i = copied - 1
+			break
+		}
+	}
+	result.WriteString(text[copied:])
+	return result.String()
+}
+
+// Whether text[start:end] doesn't continue a word on either side
+func isToken(text string, start int, end int) bool {
+	first, _ := utf8.DecodeRuneInString(text[start:end])
+	last, _ := utf8.DecodeLastRuneInString(text[start:end])
+	before, _ := utf8.DecodeLastRuneInString(text[:start])
+	after, _ := utf8.DecodeRuneInString(text[end:])
+	if start > 0 && isWordRune(first) && isWordRune(before) {
+		return false
+	}
+	return end == len(text) || !isWordRune(last) || !isWordRune(after)
+}
+
+func isWordRune(char rune) bool {
+	return char == '_' || unicode.IsLetter(char) || unicode.IsDigit(char)
+}
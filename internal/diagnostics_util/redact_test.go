@@ -0,0 +1,163 @@
+package diagnostics_util
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"gmod-cef-codec-fix-native/internal/steam_util"
+)
+
+var testLoginUsers = &steam_util.VdfLoginUsers{Users: map[uint64]steam_util.SteamUser{
+	76561197960287930: {SteamID64: 76561197960287930, AccountName: "gabelogannewell", PersonaName: "Rabscuttle"},
+	76561198000000001: {SteamID64: 76561198000000001, AccountName: "secondaccount", PersonaName: "Second"},
+	// Names that would get escaped or look like JSON once encoded
+	76561198000000002: {SteamID64: 76561198000000002, AccountName: "quoteaccount", PersonaName: `Say "hi" <&> \o/`},
+	76561198000000003: {SteamID64: 76561198000000003, AccountName: "true", PersonaName: "4"},
+}}
+
+// The JSON secrets, with the ones that end up escaped once encoded
+var testSecrets = []string{
+	"76561197960287930", "76561198000000001", "76561198000000002", "76561198000000003",
+	"gabelogannewell", "Rabscuttle", "secondaccount", "quoteaccount", `Say "hi" <&> \o/`,
+}
+
+// steam.json has every login user, each with its SteamID64
+func TestRedactedJSONIsValid(t *testing.T) {
+	var steamUsers []steam_util.LoginUser
+	for _, steamUser := range testLoginUsers.Users {
+		steamUsers = append(steamUsers, steam_util.LoginUser{SteamUser: steamUser, HasLocalConfig: true})
+	}
+	selectedUser := testLoginUsers.Users[76561198000000002]
+	value := map[string]any{
+		"Environment": map[string]any{
+			"SteamUsers": steamUsers,
+			"SteamUser":  &selectedUser,
+			// Numbers, booleans and keys that look like the short names have to survive
+			"BuildID":     4,
+			"UsingProton": true,
+			"4":           "four",
+			"Path":        "/games/4/steamapps/4000",
+		},
+		"LoginUsers": testLoginUsers,
+		"OwnerID":    uint64(76561198000000001),
+	}
+
+	redacted, err := NewRedactor(testLoginUsers).MarshalIndent(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !json.Valid(redacted) {
+		t.Fatalf("redacted JSON isn't valid:\n%s", redacted)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(redacted, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	encodedSecrets := fmt.Sprint(decoded)
+	for _, secret := range testSecrets {
+		if strings.Contains(encodedSecrets, secret) {
+			t.Errorf("%q wasn't redacted:\n%s", secret, redacted)
+		}
+	}
+	environment := decoded["Environment"].(map[string]any)
+	if environment["BuildID"] != 4.0 || environment["UsingProton"] != true || environment["<persona name 4>"] != "four" {
+		t.Errorf("values that only look like names were changed:\n%s", redacted)
+	}
+	if environment["Path"] != "/games/<persona name 4>/steamapps/4000" {
+		t.Errorf("Path = %v, want only the whole 4 replaced", environment["Path"])
+	}
+	if decoded["OwnerID"] != "<steamid64 2>" {
+		t.Errorf("OwnerID = %v, want <steamid64 2>", decoded["OwnerID"])
+	}
+}
+
+// Without secrets the output is the same as json.MarshalIndent
+func TestRedactorMarshalIndentKeepsLayout(t *testing.T) {
+	value := map[string]any{"a": []any{1, "x", nil, map[string]any{}, []any{}}, "b": map[string]any{"c": false}}
+	want, _ := json.MarshalIndent(value, "", "  ")
+	for _, redactor := range []*Redactor{nil, NewRedactor(nil)} {
+		got, err := redactor.MarshalIndent(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("MarshalIndent() =\n%s\nwant\n%s", got, want)
+		}
+	}
+}
+
+func TestRedactLog(t *testing.T) {
+	redactor := NewRedactor(testLoginUsers)
+	tests := []struct {
+		log  string
+		want string
+	}{
+		{"build 22202123 account 22202 steamid 76561197960287930", "build 22202123 account <account id 1> steamid <steamid64 1>"},
+		{"account=gabelogannewell persona=Rabscuttle", "account=<account name 1> persona=<persona name 1>"},
+		// slog quotes values with spaces or quotes Go style
+		{`persona="Say \"hi\" <&> \\o/" account=quoteaccount`, `persona="<persona name 3>" account=<account name 3>`},
+		{`persona=Say "hi" <&> \o/`, `persona=<persona name 3>`},
+		// JSON escapes differently
+		{`"persona":"Say \"hi\" \u003c\u0026\u003e \\o/"`, `"persona":"<persona name 3>"`},
+		// Short names only go as whole words
+		{"persona=4 build=4000 progress=40% using_proton=true trueish", "persona=<persona name 4> build=4000 progress=40% using_proton=<account name 4> trueish"},
+	}
+	for _, test := range tests {
+		if got := string(redactor.RedactLog([]byte(test.log))); got != test.want {
+			t.Errorf("RedactLog(%q) = %q, want %q", test.log, got, test.want)
+		}
+	}
+}
+
+func TestNilRedactor(t *testing.T) {
+	var redactor *Redactor
+	if got := string(redactor.RedactLog([]byte("gabelogannewell"))); got != "gabelogannewell" {
+		t.Errorf("nil redactor changed the contents to %q", got)
+	}
+}
+
+// Only needs a loginusers.vdf, nothing else of the Steam install has to exist
+func TestSteamRedactorWithoutGMod(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("GetSteamPath only looks in the home directory on Linux")
+	}
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+	t.Setenv("XDG_DATA_HOME", "")
+	configDir := filepath.Join(homeDir, ".steam", "steam", "config")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	loginUsersVdf := `"users"
+{
+	"76561197960287930"
+	{
+		"AccountName"		"gabelogannewell"
+		"PersonaName"		"Rabscuttle"
+		"MostRecent"		"1"
+	}
+}
+`
+	if err := os.WriteFile(filepath.Join(configDir, "loginusers.vdf"), []byte(loginUsersVdf), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	redactor, err := NewSteamRedactor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := "time=2026-10-19T00:00:00Z level=ERROR msg=\"Couldn't detect GMod\" err=\"no appmanifest\"\n" +
+		"time=2026-10-19T00:00:00Z level=INFO msg=\"Found Steam account\" account=gabelogannewell persona=Rabscuttle steamid=76561197960287930\n" +
+		"time=2026-10-19T00:00:00Z level=INFO msg=\"Reading\" path=" + filepath.Join(homeDir, ".steam", "steam", "userdata", "22202", "config") + "\n"
+	redacted := string(redactor.RedactLog([]byte(log)))
+	for _, secret := range []string{"gabelogannewell", "Rabscuttle", "76561197960287930", "22202", homeDir} {
+		if strings.Contains(redacted, secret) {
+			t.Errorf("%q wasn't redacted:\n%s", secret, redacted)
+		}
+	}
+}
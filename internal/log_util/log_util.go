@@ -0,0 +1,174 @@
+package log_util
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A log record flattened into something that is easy to keep around and show in the GUI
+type Entry struct {
+	Time    time.Time
+	Level   slog.Level
+	Message string
+	Attrs   []slog.Attr
+}
+
+func (e Entry) String() string {
+	var line strings.Builder
+	fmt.Fprintf(&line, "%v %-5v %v", e.Time.Format(time.TimeOnly), e.Level, e.Message)
+	for _, attr := range e.Attrs {
+		fmt.Fprintf(&line, " %v=%v", attr.Key, attr.Value)
+	}
+	return line.String()
+}
+
+// Keeps the most recent entries and drops the oldest ones once it is full
+type RingBuffer struct {
+	mutex     sync.Mutex
+	entries   []Entry
+	start     int
+	count     int
+	listeners []func(Entry)
+}
+
+func NewRingBuffer(capacity int) *RingBuffer {
+	return &RingBuffer{entries: make([]Entry, capacity)}
+}
+
+func (b *RingBuffer) Add(entry Entry) {
+	b.mutex.Lock()
+	if b.count < len(b.entries) {
+		b.entries[(b.start+b.count)%len(b.entries)] = entry
+		b.count++
+	} else {
+		b.entries[b.start] = entry
+		b.start = (b.start + 1) % len(b.entries)
+	}
+	listeners := b.listeners
+	b.mutex.Unlock()
+	for _, listener := range listeners {
+		listener(entry)
+	}
+}
+
+func (b *RingBuffer) Capacity() int {
+	return len(b.entries)
+}
+
+// Oldest first
+func (b *RingBuffer) Entries() []Entry {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	entries := make([]Entry, b.count)
+	for i := range entries {
+		entries[i] = b.entries[(b.start+i)%len(b.entries)]
+	}
+	return entries
+}
+
+// Called with the new entry after every Add, from whatever goroutine did the logging
+func (b *RingBuffer) OnAdd(listener func(Entry)) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.listeners = append(b.listeners, listener)
+}
+
+// slog.Handler that stores everything in a RingBuffer
+type RingHandler struct {
+	ring   *RingBuffer
+	level  slog.Leveler
+	attrs  []slog.Attr
+	prefix string
+}
+
+func NewRingHandler(ring *RingBuffer, level slog.Leveler) *RingHandler {
+	return &RingHandler{ring: ring, level: level}
+}
+
+func (h *RingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *RingHandler) Handle(ctx context.Context, record slog.Record) error {
+	entry := Entry{
+		Time:    record.Time,
+		Level:   record.Level,
+		Message: record.Message,
+		Attrs:   append([]slog.Attr{}, h.attrs...),
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		entry.Attrs = append(entry.Attrs, slog.Attr{Key: h.prefix + attr.Key, Value: attr.Value.Resolve()})
+		return true
+	})
+	h.ring.Add(entry)
+	return nil
+}
+
+func (h *RingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handler := *h
+	handler.attrs = append([]slog.Attr{}, h.attrs...)
+	for _, attr := range attrs {
+		handler.attrs = append(handler.attrs, slog.Attr{Key: h.prefix + attr.Key, Value: attr.Value.Resolve()})
+	}
+	return &handler
+}
+
+func (h *RingHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	handler := *h
+	handler.prefix = h.prefix + name + "."
+	return &handler
+}
+
+// Sends every record to all of the handlers that want it, so logs can go to the console and the GUI at once
+type FanoutHandler struct {
+	handlers []slog.Handler
+}
+
+func NewFanoutHandler(handlers ...slog.Handler) *FanoutHandler {
+	return &FanoutHandler{handlers: handlers}
+}
+
+func (h *FanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h.handlers {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *FanoutHandler) Handle(ctx context.Context, record slog.Record) error {
+	var firstErr error
+	for _, handler := range h.handlers {
+		if !handler.Enabled(ctx, record.Level) {
+			continue
+		}
+		if err := handler.Handle(ctx, record.Clone()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (h *FanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithAttrs(attrs)
+	}
+	return &FanoutHandler{handlers: handlers}
+}
+
+func (h *FanoutHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithGroup(name)
+	}
+	return &FanoutHandler{handlers: handlers}
+}
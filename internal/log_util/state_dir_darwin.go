@@ -0,0 +1,15 @@
+package log_util
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// ~/Library/Logs is where Console.app looks
+func userStateDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, "Library", "Logs"), nil
+}
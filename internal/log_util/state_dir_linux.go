@@ -0,0 +1,18 @@
+package log_util
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// $XDG_STATE_HOME, which defaults to ~/.local/state
+func userStateDir() (string, error) {
+	if stateDir := os.Getenv("XDG_STATE_HOME"); filepath.IsAbs(stateDir) {
+		return stateDir, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".local", "state"), nil
+}
@@ -0,0 +1,10 @@
+package log_util
+
+import (
+	"os"
+)
+
+// %LocalAppData%, logs shouldn't roam with the profile
+func userStateDir() (string, error) {
+	return os.UserCacheDir()
+}
@@ -0,0 +1,136 @@
+package patching_util
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Where cached manifests and hashes live, created if it doesn't exist yet
+func GetCacheDir() (string, error) {
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	cacheDir := filepath.Join(userCacheDir, "GModCEFCodecFix")
+	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		return "", err
+	}
+	return cacheDir, nil
+}
+
+// Use the cached manifest if it is newer than maxAge, otherwise download a new one.
+// If downloading fails we still fall back to the cached one no matter how old it is.
+func LoadManifest(ctx context.Context, cacheDir string, maxAge time.Duration) (PatchManifest, error) {
+	cachePath := filepath.Join(cacheDir, "manifest.json")
+	if stat, err := os.Stat(cachePath); err == nil && time.Since(stat.ModTime()) < maxAge {
+		if data, err := readCachedManifest(cachePath); err == nil {
+			return data, nil
+		}
+	}
+
+	data, fetchErr := FetchManifest(ctx)
+	if fetchErr == nil {
+		if encoded, err := json.Marshal(data); err == nil {
+			os.WriteFile(cachePath, encoded, 0o644)
+		}
+		return data, nil
+	}
+	data, err := readCachedManifest(cachePath)
+	if err != nil {
+		return nil, fetchErr
+	}
+	slog.Warn("Couldn't download the manifest, using the cached one instead", "path", cachePath, "err", fetchErr)
+	return data, nil
+}
+
+// Identifies the cached manifest by its hash and when it was downloaded, the manifest itself has no version
+func CachedManifestVersion(cacheDir string) (string, time.Time, error) {
+	cachePath := filepath.Join(cacheDir, "manifest.json")
+	stat, err := os.Stat(cachePath)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	sha, err := GetFileSHA256(cachePath)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return sha, stat.ModTime(), nil
+}
+
+func readCachedManifest(cachePath string) (PatchManifest, error) {
+	var data PatchManifest
+	encoded, err := os.ReadFile(cachePath)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(encoded, &data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Remembers file hashes by size and modification time so unchanged files don't have to be read again
+type HashCache struct {
+	path    string
+	mutex   sync.Mutex
+	entries map[string]hashCacheEntry
+}
+type hashCacheEntry struct {
+	Size    int64
+	ModTime time.Time
+	SHA256  string
+}
+
+// A missing or unreadable cache file just means starting with an empty cache
+func LoadHashCache(cacheDir string) *HashCache {
+	hashCache := &HashCache{
+		path:    filepath.Join(cacheDir, "hashes.json"),
+		entries: make(map[string]hashCacheEntry),
+	}
+	if encoded, err := os.ReadFile(hashCache.path); err == nil {
+		json.Unmarshal(encoded, &hashCache.entries)
+	}
+	return hashCache
+}
+
+func (c *HashCache) GetFileSHA256(filePath string, progress ProgressFunc) (string, error) {
+	stat, err := os.Stat(filePath)
+	if err != nil {
+		return "", err
+	}
+	c.mutex.Lock()
+	entry, exists := c.entries[filePath]
+	c.mutex.Unlock()
+	if exists && entry.Size == stat.Size() && entry.ModTime.Equal(stat.ModTime()) {
+		return entry.SHA256, nil
+	}
+
+	fileSha, err := GetFileSHA256WithProgress(filePath, progress)
+	if err != nil {
+		return "", err
+	}
+	c.mutex.Lock()
+	c.entries[filePath] = hashCacheEntry{
+		Size:    stat.Size(),
+		ModTime: stat.ModTime(),
+		SHA256:  fileSha,
+	}
+	c.mutex.Unlock()
+	return fileSha, nil
+}
+
+func (c *HashCache) Save() error {
+	c.mutex.Lock()
+	encoded, err := json.Marshal(c.entries)
+	c.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, encoded, 0o644)
+}
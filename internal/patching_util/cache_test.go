@@ -0,0 +1,144 @@
+package patching_util
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHashCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	filePath := filepath.Join(t.TempDir(), "fox.dll")
+	if err := os.WriteFile(filePath, foxOld, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(filePath, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	hashed := 0
+	progress := func(stage string, done int64, total int64) {
+		if stage == STAGE_HASHING && done == total {
+			hashed++
+		}
+	}
+	check := func(hashCache *HashCache, wantSha string, wantHashed int) {
+		t.Helper()
+		got, err := hashCache.GetFileSHA256(filePath, progress)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != wantSha {
+			t.Errorf("GetFileSHA256() = %v, want %v", got, wantSha)
+		}
+		if hashed != wantHashed {
+			t.Errorf("file was hashed %v times, want %v", hashed, wantHashed)
+		}
+	}
+
+	hashCache := LoadHashCache(cacheDir)
+	check(hashCache, sha256Hex(foxOld), 1)
+	check(hashCache, sha256Hex(foxOld), 1)
+
+	// A new modification time means the file has to be read again
+	modTime = modTime.Add(time.Second)
+	if err := os.Chtimes(filePath, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	check(hashCache, sha256Hex(foxOld), 2)
+
+	// So does a different size, even if the modification time was put back
+	if err := os.WriteFile(filePath, []byte("The quick brown fox jumps"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filePath, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	check(hashCache, sha256Hex([]byte("The quick brown fox jumps")), 3)
+
+	// Saved entries are hits for the next run
+	if err := hashCache.Save(); err != nil {
+		t.Fatal(err)
+	}
+	check(LoadHashCache(cacheDir), sha256Hex([]byte("The quick brown fox jumps")), 3)
+}
+
+func TestLoadHashCacheCorrupt(t *testing.T) {
+	cacheDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(cacheDir, "hashes.json"), []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	filePath := filepath.Join(t.TempDir(), "fox.dll")
+	if err := os.WriteFile(filePath, foxOld, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := LoadHashCache(cacheDir).GetFileSHA256(filePath, nil); err != nil || got != sha256Hex(foxOld) {
+		t.Errorf("GetFileSHA256() with a corrupt cache = %v, %v", got, err)
+	}
+}
+
+// A fresh cached manifest is used without going online
+func TestLoadManifestCached(t *testing.T) {
+	cacheDir := t.TempDir()
+	writeCachedManifest(t, cacheDir)
+	manifest, err := LoadManifest(context.Background(), cacheDir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertCachedManifest(t, manifest)
+}
+
+// A server that never answers mustn't hang the run, the old manifest is better than nothing
+func TestLoadManifestStalledServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	defer func(url string) { manifestUrl = url }(manifestUrl)
+	manifestUrl = server.URL + "/manifest.json"
+
+	cacheDir := t.TempDir()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := LoadManifest(ctx, cacheDir, 0); err == nil {
+		t.Error("LoadManifest() without a cached manifest should fail")
+	}
+
+	writeCachedManifest(t, cacheDir)
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	manifest, err := LoadManifest(ctx, cacheDir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertCachedManifest(t, manifest)
+}
+
+func writeCachedManifest(t *testing.T, cacheDir string) {
+	t.Helper()
+	cached := PatchManifest{"linux": {"public": {"bin/fox.so": {Fixed: sha256Hex(foxNew), Original: sha256Hex(foxOld)}}}}
+	encoded, err := json.Marshal(cached)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cacheDir, "manifest.json"), encoded, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertCachedManifest(t *testing.T, manifest PatchManifest) {
+	t.Helper()
+	branch, err := manifest.GetBranch("linux", "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if branch["bin/fox.so"].Fixed != sha256Hex(foxNew) {
+		t.Errorf("LoadManifest() = %v, want the cached manifest", manifest)
+	}
+}
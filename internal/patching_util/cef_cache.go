@@ -0,0 +1,142 @@
+package patching_util
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Where GMod's CEF keeps its cache, relative to the game directory
+var gameCEFCacheDirs = []string{
+	"chromiumcache",
+	filepath.Join("garrysmod", "cache", "chromium"),
+}
+
+// Where CEF keeps its cache inside a Proton prefix
+var prefixCEFCacheDirs = []string{
+	filepath.Join("drive_c", "users", "steamuser", "AppData", "Local", "CEF"),
+}
+
+type cefCacheRoot struct {
+	path       string
+	candidates []string
+}
+
+type CEFCacheDir struct {
+	Path string
+	Size int64
+	// Only the link gets removed, never what it points to
+	IsLink bool
+}
+
+// Stale cache entries break videos and pages after the codecs change, so these should be cleared after patching.
+// protonPrefix is the pfx directory, or empty if the game doesn't run under Proton.
+func FindCEFCacheDirs(gamePath string, protonPrefix string) ([]CEFCacheDir, error) {
+	var cacheDirs []CEFCacheDir
+	roots := []cefCacheRoot{{gamePath, gameCEFCacheDirs}}
+	if protonPrefix != "" {
+		roots = append(roots, cefCacheRoot{protonPrefix, prefixCEFCacheDirs})
+	}
+	for _, root := range roots {
+		for _, candidate := range root.candidates {
+			cacheDir, err := resolveInside(root.path, candidate)
+			if err != nil {
+				return nil, err
+			}
+			if cacheDir != nil {
+				cacheDirs = append(cacheDirs, *cacheDir)
+			}
+		}
+	}
+	return cacheDirs, nil
+}
+
+func RemoveCEFCacheDirs(cacheDirs []CEFCacheDir) error {
+	for _, cacheDir := range cacheDirs {
+		remove := os.RemoveAll
+		if cacheDir.IsLink {
+			remove = os.Remove
+		}
+		if err := remove(cacheDir.Path); err != nil {
+			return fmt.Errorf("Couldn't delete %s: %w", cacheDir.Path, err)
+		}
+	}
+	return nil
+}
+
+// Resolve symlinks in root/relPath and refuse anything that ends up outside of root or is root itself.
+// If relPath itself is a symlink only the link is returned, so deleting it can't touch anything else.
+// Returns nil if it doesn't exist.
+func resolveInside(root string, relPath string) (*CEFCacheDir, error) {
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, nil
+	}
+	// Links further up, like garrysmod/cache pointing somewhere else, decide where the cache really is
+	candidatePath := filepath.Join(root, relPath)
+	resolvedParent, err := filepath.EvalSymlinks(filepath.Dir(candidatePath))
+	if err != nil {
+		return nil, nil
+	}
+	cachePath := filepath.Join(resolvedParent, filepath.Base(candidatePath))
+	if !strictlyInside(resolvedRoot, cachePath) {
+		return nil, fmt.Errorf("Refusing to touch %s, it resolves to %s which is outside of %s", candidatePath, cachePath, root)
+	}
+	lstat, err := os.Lstat(cachePath)
+	if err != nil {
+		return nil, nil
+	}
+	if lstat.Mode()&fs.ModeSymlink != 0 {
+		target, err := filepath.EvalSymlinks(cachePath)
+		if err != nil {
+			// Dangling, nothing to clear
+			return nil, nil
+		}
+		if !strictlyInside(resolvedRoot, target) {
+			return nil, fmt.Errorf("Refusing to touch %s, it links to %s which is outside of %s", candidatePath, target, root)
+		}
+		if stat, err := os.Stat(target); err != nil || !stat.IsDir() {
+			return nil, nil
+		}
+		size, err := dirSize(target)
+		if err != nil {
+			return nil, err
+		}
+		return &CEFCacheDir{Path: cachePath, Size: size, IsLink: true}, nil
+	}
+	if !lstat.IsDir() {
+		return nil, nil
+	}
+	size, err := dirSize(cachePath)
+	if err != nil {
+		return nil, err
+	}
+	return &CEFCacheDir{Path: cachePath, Size: size}, nil
+}
+
+// Whether path is below dir, dir itself doesn't count
+func strictlyInside(dir string, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// WalkDir doesn't follow symlinks so nothing outside the directory gets counted
+func dirSize(dirPath string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dirPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,164 @@
+package patching_util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, path string, size int) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCEFCacheDirs(t *testing.T) {
+	gamePath := t.TempDir()
+	writeTestFile(t, filepath.Join(gamePath, "chromiumcache", "Cache", "data_1"), 100)
+	writeTestFile(t, filepath.Join(gamePath, "garrysmod", "cache", "chromium", "index"), 20)
+	writeTestFile(t, filepath.Join(gamePath, "garrysmod", "cache", "other", "keep"), 5)
+	protonPrefix := t.TempDir()
+	writeTestFile(t, filepath.Join(protonPrefix, prefixCEFCacheDirs[0], "Cache", "data_0"), 7)
+
+	cacheDirs, err := FindCEFCacheDirs(gamePath, protonPrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sizes := make(map[string]int64)
+	for _, cacheDir := range cacheDirs {
+		sizes[cacheDir.Path] = cacheDir.Size
+	}
+	resolvedGamePath, _ := filepath.EvalSymlinks(gamePath)
+	resolvedPrefix, _ := filepath.EvalSymlinks(protonPrefix)
+	want := map[string]int64{
+		filepath.Join(resolvedGamePath, "chromiumcache"):                  100,
+		filepath.Join(resolvedGamePath, "garrysmod", "cache", "chromium"): 20,
+		filepath.Join(resolvedPrefix, prefixCEFCacheDirs[0]):              7,
+	}
+	if len(sizes) != len(want) {
+		t.Fatalf("FindCEFCacheDirs() = %v, want %v", sizes, want)
+	}
+	for path, size := range want {
+		if sizes[path] != size {
+			t.Errorf("size of %v = %v, want %v", path, sizes[path], size)
+		}
+	}
+
+	if err := RemoveCEFCacheDirs(cacheDirs); err != nil {
+		t.Fatal(err)
+	}
+	for path := range want {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%v still exists", path)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(gamePath, "garrysmod", "cache", "other", "keep")); err != nil {
+		t.Errorf("a file next to the cache was deleted: %v", err)
+	}
+}
+
+// A cache directory that is a symlink out of the game directory must not get anything outside deleted
+func TestCEFCacheDirsRefuseSymlinkOutside(t *testing.T) {
+	outside := t.TempDir()
+	outsideFile := filepath.Join(outside, "important")
+	writeTestFile(t, outsideFile, 10)
+
+	tests := map[string]func(gamePath string) error{
+		"absolute link": func(gamePath string) error {
+			return os.Symlink(outside, filepath.Join(gamePath, "chromiumcache"))
+		},
+		"relative link with ..": func(gamePath string) error {
+			if err := os.MkdirAll(filepath.Join(gamePath, "garrysmod"), 0o755); err != nil {
+				return err
+			}
+			relOutside, err := filepath.Rel(filepath.Join(gamePath, "garrysmod"), outside)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(relOutside, filepath.Join(gamePath, "garrysmod", "cache"))
+		},
+	}
+	for name, makeLink := range tests {
+		t.Run(name, func(t *testing.T) {
+			gamePath := t.TempDir()
+			if err := makeLink(gamePath); err != nil {
+				t.Skipf("can't create symlinks here: %v", err)
+			}
+			// Either way the chromium directory ends up outside of the game directory
+			writeTestFile(t, filepath.Join(outside, "chromium", "index"), 10)
+
+			cacheDirs, err := FindCEFCacheDirs(gamePath, "")
+			if err == nil {
+				t.Errorf("FindCEFCacheDirs() = %v, want an error", cacheDirs)
+				RemoveCEFCacheDirs(cacheDirs)
+			}
+			if _, err := os.Stat(outsideFile); err != nil {
+				t.Errorf("file outside the game directory is gone: %v", err)
+			}
+			if _, err := os.Stat(filepath.Join(outside, "chromium", "index")); err != nil {
+				t.Errorf("cache outside the game directory is gone: %v", err)
+			}
+		})
+	}
+}
+
+// A cache directory linking back to the game directory would get the whole install deleted
+func TestCEFCacheDirsRefuseLinkToRoot(t *testing.T) {
+	tests := map[string]func(gamePath string) string{
+		"link to .":         func(gamePath string) string { return "." },
+		"link to game root": func(gamePath string) string { return gamePath },
+		"link to ../root": func(gamePath string) string {
+			return filepath.Join("..", filepath.Base(gamePath))
+		},
+	}
+	for name, target := range tests {
+		t.Run(name, func(t *testing.T) {
+			gamePath := t.TempDir()
+			gameFile := filepath.Join(gamePath, "garrysmod", "bin", "client.so")
+			writeTestFile(t, gameFile, 10)
+			if err := os.Symlink(target(gamePath), filepath.Join(gamePath, "chromiumcache")); err != nil {
+				t.Skipf("can't create symlinks here: %v", err)
+			}
+
+			cacheDirs, err := FindCEFCacheDirs(gamePath, "")
+			if err == nil {
+				t.Errorf("FindCEFCacheDirs() = %v, want an error", cacheDirs)
+				RemoveCEFCacheDirs(cacheDirs)
+			}
+			if _, err := os.Stat(gameFile); err != nil {
+				t.Errorf("game file is gone: %v", err)
+			}
+		})
+	}
+}
+
+// Links that stay inside the game directory are fine, but only the link gets removed
+func TestCEFCacheDirsSymlinkInside(t *testing.T) {
+	gamePath := t.TempDir()
+	sharedFile := filepath.Join(gamePath, "shared", "cache", "data_1")
+	writeTestFile(t, sharedFile, 30)
+	linkPath := filepath.Join(gamePath, "chromiumcache")
+	if err := os.Symlink(filepath.Join(gamePath, "shared"), linkPath); err != nil {
+		t.Skipf("can't create symlinks here: %v", err)
+	}
+	cacheDirs, err := FindCEFCacheDirs(gamePath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cacheDirs) != 1 || !cacheDirs[0].IsLink || cacheDirs[0].Size != 30 {
+		t.Fatalf("FindCEFCacheDirs() = %+v, want the link", cacheDirs)
+	}
+	if err := RemoveCEFCacheDirs(cacheDirs); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(linkPath); !os.IsNotExist(err) {
+		t.Errorf("link still exists: %v", err)
+	}
+	if _, err := os.Stat(sharedFile); err != nil {
+		t.Errorf("file behind the link is gone: %v", err)
+	}
+}
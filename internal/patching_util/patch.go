@@ -0,0 +1,175 @@
+package patching_util
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Patch a single game file in place.
+// The file has to match the original hash from the manifest and the result has to match the fixed hash,
+// otherwise the file is left alone. progress can be nil, cancelling ctx stops the download.
+func PatchFile(ctx context.Context, filePath string, patchInfo PatchInfo, progress ProgressFunc) error {
+	if progress == nil {
+		progress = func(string, int64, int64) {}
+	}
+	original, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("Couldn't read %s: %w", filePath, err)
+	}
+	originalSha := fmt.Sprintf("%X", sha256.Sum256(original))
+	if strings.EqualFold(originalSha, patchInfo.Fixed) {
+		return nil
+	}
+	if !strings.EqualFold(originalSha, patchInfo.Original) {
+		return fmt.Errorf("%s doesn't match the original file from Steam, try verifying the game files", filePath)
+	}
+
+	patch, err := downloadPatch(ctx, patchInfo, progress)
+	if err != nil {
+		return err
+	}
+	fixed, err := applyBsdiff(original, patch, progress)
+	if err != nil {
+		return fmt.Errorf("Couldn't apply patch to %s: %w", filePath, err)
+	}
+	if fixedSha := fmt.Sprintf("%X", sha256.Sum256(fixed)); !strings.EqualFold(fixedSha, patchInfo.Fixed) {
+		return fmt.Errorf("Patched %s doesn't match the expected hash, got %s", filePath, fixedSha)
+	}
+	return replaceFile(filePath, fixed)
+}
+
+// Counts bytes as they are read so downloads can report progress
+type progressReader struct {
+	reader   io.Reader
+	done     int64
+	total    int64
+	progress ProgressFunc
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.done += int64(n)
+	r.progress(STAGE_DOWNLOADING, r.done, r.total)
+	return n, err
+}
+
+func downloadPatch(ctx context.Context, patchInfo PatchInfo, progress ProgressFunc) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, patchInfo.PatchUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Error: received non-200 response code downloading %s: %v", patchInfo.PatchUrl, resp.StatusCode)
+	}
+	patch, err := io.ReadAll(&progressReader{reader: resp.Body, total: resp.ContentLength, progress: progress})
+	if err != nil {
+		return nil, err
+	}
+	if patchSha := fmt.Sprintf("%X", sha256.Sum256(patch)); !strings.EqualFold(patchSha, patchInfo.Patch) {
+		return nil, fmt.Errorf("Downloaded patch %s doesn't match the expected hash, got %s", patchInfo.PatchUrl, patchSha)
+	}
+	return patch, nil
+}
+
+// Write next to the file and rename over it so a failure never leaves a half written file behind
+func replaceFile(filePath string, data []byte) error {
+	stat, err := os.Stat(filePath)
+	if err != nil {
+		return err
+	}
+	tmpFile, err := os.CreateTemp(filepath.Dir(filePath), filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpFile.Name(), stat.Mode()); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), filePath)
+}
+
+// Apply a patch in the BSDIFF40 format, which is what python's bsdiff4 produces
+func applyBsdiff(old []byte, patch []byte, progress ProgressFunc) ([]byte, error) {
+	if len(patch) < 32 || string(patch[:8]) != "BSDIFF40" {
+		return nil, errors.New("not a bsdiff patch")
+	}
+	ctrlLen := bsdiffOfftin(patch[8:16])
+	diffLen := bsdiffOfftin(patch[16:24])
+	newSize := bsdiffOfftin(patch[24:32])
+	if ctrlLen < 0 || diffLen < 0 || newSize < 0 || ctrlLen > int64(len(patch))-32 || diffLen > int64(len(patch))-32-ctrlLen {
+		return nil, errors.New("corrupt bsdiff header")
+	}
+	ctrlReader := bzip2.NewReader(bytes.NewReader(patch[32 : 32+ctrlLen]))
+	diffReader := bzip2.NewReader(bytes.NewReader(patch[32+ctrlLen : 32+ctrlLen+diffLen]))
+	extraReader := bzip2.NewReader(bytes.NewReader(patch[32+ctrlLen+diffLen:]))
+
+	newData := make([]byte, newSize)
+	var oldPos, newPos int64
+	buf := make([]byte, 8)
+	for newPos < newSize {
+		// Each control entry is: bytes to add from diff, bytes to copy from extra, how far to seek in old
+		var ctrl [3]int64
+		for i := range ctrl {
+			if _, err := io.ReadFull(ctrlReader, buf); err != nil {
+				return nil, fmt.Errorf("reading control block: %w", err)
+			}
+			ctrl[i] = bsdiffOfftin(buf)
+		}
+
+		if ctrl[0] < 0 || ctrl[0] > newSize-newPos {
+			return nil, errors.New("corrupt bsdiff control block")
+		}
+		if _, err := io.ReadFull(diffReader, newData[newPos:newPos+ctrl[0]]); err != nil {
+			return nil, fmt.Errorf("reading diff block: %w", err)
+		}
+		for i := int64(0); i < ctrl[0]; i++ {
+			if oldPos+i >= 0 && oldPos+i < int64(len(old)) {
+				newData[newPos+i] += old[oldPos+i]
+			}
+		}
+		newPos += ctrl[0]
+		oldPos += ctrl[0]
+
+		if ctrl[1] < 0 || ctrl[1] > newSize-newPos {
+			return nil, errors.New("corrupt bsdiff control block")
+		}
+		if _, err := io.ReadFull(extraReader, newData[newPos:newPos+ctrl[1]]); err != nil {
+			return nil, fmt.Errorf("reading extra block: %w", err)
+		}
+		newPos += ctrl[1]
+		oldPos += ctrl[2]
+		progress(STAGE_PATCHING, newPos, newSize)
+	}
+	return newData, nil
+}
+
+// bsdiff stores its integers as sign and magnitude rather than two's complement
+func bsdiffOfftin(buf []byte) int64 {
+	value := int64(binary.LittleEndian.Uint64(buf) & 0x7FFFFFFFFFFFFFFF)
+	if buf[7]&0x80 != 0 {
+		value = -value
+	}
+	return value
+}
@@ -0,0 +1,229 @@
+package patching_util
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// The testdata patches are built by hand with python's bz2 module. fox.bsdiff turns foxOld into foxNew
+// with two control entries: diff 10 bytes, add "red", skip "brown", then diff " fox" into " FOX" and add "!!".
+var (
+	foxOld = []byte("The quick brown fox")
+	foxNew = []byte("The quick red FOX!!")
+)
+
+func readTestPatch(t *testing.T, name string) []byte {
+	t.Helper()
+	patch, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return patch
+}
+
+func sha256Hex(data []byte) string {
+	return fmt.Sprintf("%X", sha256.Sum256(data))
+}
+
+func TestApplyBsdiff(t *testing.T) {
+	var lastDone, lastTotal int64
+	fixed, err := applyBsdiff(foxOld, readTestPatch(t, "fox.bsdiff"), func(stage string, done int64, total int64) {
+		lastDone, lastTotal = done, total
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(fixed, foxNew) {
+		t.Errorf("applyBsdiff() = %q, want %q", fixed, foxNew)
+	}
+	if lastDone != int64(len(foxNew)) || lastTotal != int64(len(foxNew)) {
+		t.Errorf("last progress = %v/%v, want %v/%v", lastDone, lastTotal, len(foxNew), len(foxNew))
+	}
+}
+
+// Broken patches have to fail cleanly, a panic would take the whole tool down mid-patch
+func TestApplyBsdiffCorrupt(t *testing.T) {
+	fox := readTestPatch(t, "fox.bsdiff")
+	badMagic := bytes.Clone(fox)
+	copy(badMagic, "BSDIFF41")
+	hugeCtrl := bytes.Clone(fox)
+	copy(hugeCtrl[8:16], []byte{0xFF, 0xFF, 0xFF, 0xFF, 0, 0, 0, 0})
+	negativeSize := bytes.Clone(fox)
+	negativeSize[31] |= 0x80
+
+	tests := map[string][]byte{
+		"empty":                  nil,
+		"bad magic":              badMagic,
+		"truncated header":       fox[:31],
+		"header only":            fox[:32],
+		"truncated blocks":       fox[:len(fox)-20],
+		"control past the patch": hugeCtrl,
+		"negative new size":      negativeSize,
+		"negative diff length":   readTestPatch(t, "negative_ctrl.bsdiff"),
+		"negative extra length":  readTestPatch(t, "negative_extra.bsdiff"),
+		"extra past the end":     readTestPatch(t, "ctrl_past_end.bsdiff"),
+	}
+	for name, patch := range tests {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Fatalf("applyBsdiff panicked: %v", r)
+				}
+			}()
+			if fixed, err := applyBsdiff(foxOld, patch, func(string, int64, int64) {}); err == nil {
+				t.Errorf("applyBsdiff() = %q, want an error", fixed)
+			}
+		})
+	}
+}
+
+func TestBsdiffOfftin(t *testing.T) {
+	tests := []struct {
+		buf  []byte
+		want int64
+	}{
+		{[]byte{0, 0, 0, 0, 0, 0, 0, 0}, 0},
+		{[]byte{1, 0, 0, 0, 0, 0, 0, 0}, 1},
+		{[]byte{1, 0, 0, 0, 0, 0, 0, 0x80}, -1},
+		{[]byte{0x34, 0x12, 0, 0, 0, 0, 0, 0}, 0x1234},
+		{[]byte{0x34, 0x12, 0, 0, 0, 0, 0, 0x80}, -0x1234},
+		// Negative zero is still zero
+		{[]byte{0, 0, 0, 0, 0, 0, 0, 0x80}, 0},
+		{[]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x7F}, 0x7FFFFFFFFFFFFFFF},
+		{[]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}, -0x7FFFFFFFFFFFFFFF},
+	}
+	for _, test := range tests {
+		if got := bsdiffOfftin(test.buf); got != test.want {
+			t.Errorf("bsdiffOfftin(% X) = %v, want %v", test.buf, got, test.want)
+		}
+	}
+}
+
+func TestPatchFile(t *testing.T) {
+	patch := readTestPatch(t, "fox.bsdiff")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(patch)
+	}))
+	defer server.Close()
+	patchInfo := PatchInfo{
+		Original: sha256Hex(foxOld),
+		Fixed:    sha256Hex(foxNew),
+		Patch:    sha256Hex(patch),
+		PatchUrl: server.URL + "/fox.bsdiff",
+	}
+
+	filePath := filepath.Join(t.TempDir(), "fox.dll")
+	if err := os.WriteFile(filePath, foxOld, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := PatchFile(context.Background(), filePath, patchInfo, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(filePath); !bytes.Equal(got, foxNew) {
+		t.Errorf("patched file = %q, want %q", got, foxNew)
+	}
+	// Already patched files are left alone
+	if err := PatchFile(context.Background(), filePath, patchInfo, nil); err != nil {
+		t.Errorf("PatchFile() on a patched file = %v", err)
+	}
+
+	unknown := []byte("The quick brown cat")
+	if err := os.WriteFile(filePath, unknown, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := PatchFile(context.Background(), filePath, patchInfo, nil); err == nil {
+		t.Error("PatchFile() on an unknown file should fail")
+	}
+	if got, _ := os.ReadFile(filePath); !bytes.Equal(got, unknown) {
+		t.Errorf("unknown file was changed to %q", got)
+	}
+
+	tamperedInfo := patchInfo
+	tamperedInfo.Patch = sha256Hex([]byte("something else"))
+	if err := os.WriteFile(filePath, foxOld, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := PatchFile(context.Background(), filePath, tamperedInfo, nil); err == nil {
+		t.Error("PatchFile() with a patch that doesn't match its hash should fail")
+	}
+	if got, _ := os.ReadFile(filePath); !bytes.Equal(got, foxOld) {
+		t.Errorf("file was changed to %q by a rejected patch", got)
+	}
+}
+
+func TestPatchFileCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	patchInfo := PatchInfo{Original: sha256Hex(foxOld), Fixed: sha256Hex(foxNew), PatchUrl: server.URL + "/fox.bsdiff"}
+
+	filePath := filepath.Join(t.TempDir(), "fox.dll")
+	if err := os.WriteFile(filePath, foxOld, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := PatchFile(ctx, filePath, patchInfo, nil); err == nil {
+		t.Error("PatchFile() with a stalled download should fail")
+	}
+	if got, _ := os.ReadFile(filePath); !bytes.Equal(got, foxOld) {
+		t.Errorf("file was changed to %q by a failed download", got)
+	}
+}
+
+func TestReplaceFile(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "fox.dll")
+	if err := os.WriteFile(filePath, foxOld, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := replaceFile(filePath, foxNew); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(filePath); !bytes.Equal(got, foxNew) {
+		t.Errorf("replaced file = %q, want %q", got, foxNew)
+	}
+	if stat, err := os.Stat(filePath); err != nil || stat.Mode().Perm() != 0o755 {
+		t.Errorf("replaced file mode = %v, %v, want the original 0755", stat.Mode(), err)
+	}
+	assertNoTempFiles(t, dir)
+}
+
+func TestReplaceFileFailure(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can write to read-only directories")
+	}
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "fox.dll")
+	if err := os.WriteFile(filePath, foxOld, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Nothing can be created next to the file, so writing the replacement fails
+	if err := os.Chmod(dir, 0o555); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(dir, 0o755)
+	if err := replaceFile(filePath, foxNew); err == nil {
+		t.Fatal("replaceFile() in a read-only directory should fail")
+	}
+	if got, _ := os.ReadFile(filePath); !bytes.Equal(got, foxOld) {
+		t.Errorf("original file = %q after a failed replace, want %q", got, foxOld)
+	}
+	assertNoTempFiles(t, dir)
+}
+
+func assertNoTempFiles(t *testing.T, dir string) {
+	t.Helper()
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*.tmp")); len(matches) > 0 {
+		t.Errorf("temporary files left behind: %v", matches)
+	}
+}
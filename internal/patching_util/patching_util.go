@@ -1,15 +1,30 @@
 package patching_util
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"sort"
+	"time"
 	// "github.com/sanity-io/litter"
 )
 
+// TODO figure out good CDN for this stuff?
+// A variable so tests can point it at a local server
+var manifestUrl = "https://raw.githubusercontent.com/solsticegamestudios/GModCEFCodecFix/master/manifest.json"
+
+// Without these a stalled connection hangs the run (or the game launch in wrapper mode) forever
+const (
+	MANIFEST_TIMEOUT = 15 * time.Second
+	DOWNLOAD_TIMEOUT = 2 * time.Minute
+)
+
+var httpClient = &http.Client{Timeout: DOWNLOAD_TIMEOUT}
+
 type PatchManifest map[string]PlatformPatchManifest
 type PlatformPatchManifest map[string]BranchPatchManifest
 type BranchPatchManifest map[string]PatchInfo
@@ -20,10 +35,23 @@ type PatchInfo struct {
 	PatchUrl string `json:"patch-url"`
 }
 
-func GetManifest(platform, branch string) (BranchPatchManifest, error) {
+func GetManifest(ctx context.Context, platform, branch string) (BranchPatchManifest, error) {
+	data, err := FetchManifest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return data.GetBranch(platform, branch)
+}
+
+func FetchManifest(ctx context.Context) (PatchManifest, error) {
 	var data PatchManifest
-	// TODO figure out good CDN for this stuff?
-	resp, err := http.Get("https://raw.githubusercontent.com/solsticegamestudios/GModCEFCodecFix/master/manifest.json")
+	ctx, cancel := context.WithTimeout(ctx, MANIFEST_TIMEOUT)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, manifestUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -40,7 +68,11 @@ func GetManifest(platform, branch string) (BranchPatchManifest, error) {
 	if err != nil {
 		return nil, err
 	}
-	platformManifest, exists := data[platform]
+	return data, nil
+}
+
+func (m PatchManifest) GetBranch(platform, branch string) (BranchPatchManifest, error) {
+	platformManifest, exists := m[platform]
 	if !exists {
 		return nil, fmt.Errorf("Error: platform: \"%v\" not found in manifest.", platform)
 	}
@@ -52,7 +84,30 @@ func GetManifest(platform, branch string) (BranchPatchManifest, error) {
 	return branchManifest, nil
 }
 
+// Branches that have patches for the given platform
+func (m PatchManifest) Branches(platform string) []string {
+	var branches []string
+	for branch := range m[platform] {
+		branches = append(branches, branch)
+	}
+	sort.Strings(branches)
+	return branches
+}
+
+// Reports how far along a stage of work on a file is, total is -1 when it isn't known
+type ProgressFunc func(stage string, done int64, total int64)
+
+const (
+	STAGE_HASHING     = "hashing"
+	STAGE_DOWNLOADING = "downloading"
+	STAGE_PATCHING    = "patching"
+)
+
 func GetFileSHA256(filePath string) (string, error) {
+	return GetFileSHA256WithProgress(filePath, nil)
+}
+
+func GetFileSHA256WithProgress(filePath string, progress ProgressFunc) (string, error) {
 	fileSHA256 := sha256.New()
 
 	file, err := os.Open(filePath)
@@ -61,6 +116,11 @@ func GetFileSHA256(filePath string) (string, error) {
 	}
 	defer file.Close()
 
+	var total int64 = -1
+	if stat, err := file.Stat(); err == nil {
+		total = stat.Size()
+	}
+	var done int64
 	buffer := make([]byte, 10485760)
 	for {
 		n, err := file.Read(buffer)
@@ -71,6 +131,10 @@ func GetFileSHA256(filePath string) (string, error) {
 			break
 		}
 		fileSHA256.Write(buffer[:n])
+		done += int64(n)
+		if progress != nil {
+			progress(STAGE_HASHING, done, total)
+		}
 	}
 	return fmt.Sprintf("%X", fileSHA256.Sum(nil)), nil
 }
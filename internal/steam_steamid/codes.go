@@ -0,0 +1,189 @@
+package steam_steamid
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Custom profile URLs (steamcommunity.com/id/<name>) can only be resolved by asking Steam
+var ErrVanityURL = errors.New("custom profile URLs can't be resolved offline")
+
+// Invite codes are the account ID in hex with the digits swapped for letters that can't spell anything
+const (
+	inviteCodeHex     = "0123456789abcdef"
+	inviteCodeLetters = "bcdfghjkmnpqrtvw"
+	InviteURLPrefix   = "https://s.team/p/"
+)
+
+var (
+	inviteCodeEncoder = strings.NewReplacer(pairs(inviteCodeHex, inviteCodeLetters)...)
+	inviteCodeDecoder = strings.NewReplacer(pairs(inviteCodeLetters, inviteCodeHex)...)
+	inviteURLRegex    = regexp.MustCompile(`^(?:https?://)?s\.team/p/([` + inviteCodeLetters + `]{1,8})-?([` + inviteCodeLetters + `]{0,8})(?:/[A-Za-z0-9]*)?/?$`)
+	// Without the link only the dashed form InviteCode renders, or short words would parse as SteamIDs
+	inviteCodeRegex = regexp.MustCompile(`^([` + inviteCodeLetters + `]{1,4})-([` + inviteCodeLetters + `]{1,4})$`)
+)
+
+// CS friend codes use base32 without the characters that are easy to mix up
+const csFriendCodeChars = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+var csFriendCodeRegex = regexp.MustCompile(`^[` + csFriendCodeChars + `]{5}-[` + csFriendCodeChars + `]{4}$`)
+
+var profileURLRegex = regexp.MustCompile(`^(?:https?://)?(?:www\.)?steamcommunity\.com/(profiles|gid|id)/([^/?#]+)/?(?:[/?#].*)?$`)
+
+func pairs(from string, to string) []string {
+	var replacements []string
+	for i := range from {
+		replacements = append(replacements, from[i:i+1], to[i:i+1])
+	}
+	return replacements
+}
+
+// InviteCode renders the short code from a friend invite link, e.g. "cv-dgb"
+func (sid SteamID) InviteCode() (string, error) {
+	if sid.Type != TypeIndividual || !sid.IsValid() {
+		return "", fmt.Errorf("can't get an invite code for %v", sid.Steam3())
+	}
+	inviteCode := inviteCodeEncoder.Replace(strconv.FormatUint(uint64(sid.AccountID), 16))
+	if split := len(inviteCode) / 2; split > 0 {
+		inviteCode = inviteCode[:split] + "-" + inviteCode[split:]
+	}
+	return inviteCode, nil
+}
+
+// InviteURL renders the s.team friend invite link
+func (sid SteamID) InviteURL() (string, error) {
+	inviteCode, err := sid.InviteCode()
+	if err != nil {
+		return "", err
+	}
+	return InviteURLPrefix + inviteCode, nil
+}
+
+// ParseInviteCode accepts the whole s.team link, or the code on its own exactly as InviteCode renders it,
+// e.g. "cv-dgb". Invite codes don't say which universe they are for, so that has to be passed in
+// (almost always UniversePublic).
+func ParseInviteCode(inviteCode string, universe Universe) (*SteamID, error) {
+	mat := inviteURLRegex.FindStringSubmatch(inviteCode)
+	bareCode := mat == nil
+	if bareCode {
+		if mat = inviteCodeRegex.FindStringSubmatch(inviteCode); mat == nil {
+			return nil, ErrMalformed
+		}
+	}
+	accountId, err := strconv.ParseUint(inviteCodeDecoder.Replace(mat[1]+mat[2]), 16, 32)
+	if err != nil {
+		return nil, ErrAccountIDOverflow
+	}
+	sid := NewSteamIDFromParts(universe, TypeIndividual, InstanceDesktop, uint32(accountId))
+	if bareCode {
+		if canonical, err := sid.InviteCode(); err != nil || canonical != inviteCode {
+			return nil, ErrMalformed
+		}
+	}
+	return sid, nil
+}
+
+// CSFriendCode renders the friend code Counter-Strike shows, e.g. "SUCVS-FADA"
+func (sid SteamID) CSFriendCode() (string, error) {
+	if sid.Type != TypeIndividual || !sid.IsValid() {
+		return "", fmt.Errorf("can't get a friend code for %v", sid.Steam3())
+	}
+	// Every nibble of the 64-bit ID is followed by one bit of this hash
+	hashInput := binary.LittleEndian.AppendUint32(nil, sid.AccountID)
+	hashInput = append(hashInput, "OGSC"...)
+	hashSum := md5.Sum(hashInput)
+	hash := binary.LittleEndian.Uint32(hashSum[:4])
+
+	steamId64 := sid.SteamID64()
+	var result uint64
+	for i := 0; i < 8; i++ {
+		idNibble := (steamId64 >> (i * 4)) & 0xF
+		hashBit := uint64(hash>>i) & 1
+		a := result<<4 | idNibble
+		result = (result>>28)<<32 | a
+		result = (result>>31)<<32 | (a<<1 | hashBit)
+	}
+	result = bits.ReverseBytes64(result)
+
+	var friendCode strings.Builder
+	for i := 0; i < 13; i++ {
+		if i == 4 || i == 9 {
+			friendCode.WriteByte('-')
+		}
+		friendCode.WriteByte(csFriendCodeChars[result&31])
+		result >>= 5
+	}
+	// The first group is always AAAA
+	return friendCode.String()[5:], nil
+}
+
+// ParseCSFriendCode turns a Counter-Strike friend code back into a SteamID.
+// Like invite codes they only hold the account ID, so the universe has to be passed in.
+func ParseCSFriendCode(friendCode string, universe Universe) (*SteamID, error) {
+	if !csFriendCodeRegex.MatchString(friendCode) {
+		return nil, ErrMalformed
+	}
+	code := strings.ReplaceAll("AAAA"+friendCode, "-", "")
+	var result uint64
+	for i := 0; i < len(code); i++ {
+		result |= uint64(strings.IndexByte(csFriendCodeChars, code[i])) << (5 * i)
+	}
+	result = bits.ReverseBytes64(result)
+
+	var accountId uint32
+	for i := 0; i < 8; i++ {
+		result >>= 1
+		accountId = accountId<<4 | uint32(result&0xF)
+		result >>= 4
+	}
+	sid := NewSteamIDFromParts(universe, TypeIndividual, InstanceDesktop, accountId)
+	// The hash bits make most typos detectable, so check them by encoding it again
+	if rendered, err := sid.CSFriendCode(); err != nil || rendered != friendCode {
+		return nil, ErrMalformed
+	}
+	return sid, nil
+}
+
+// ProfileURL renders the steamcommunity.com link for a user or a group
+func (sid SteamID) ProfileURL() (string, error) {
+	switch sid.Type {
+	case TypeIndividual:
+		return fmt.Sprintf("https://steamcommunity.com/profiles/%v", sid.SteamID64()), nil
+	case TypeClan:
+		return fmt.Sprintf("https://steamcommunity.com/gid/%v", sid.SteamID64()), nil
+	}
+	return "", fmt.Errorf("%v doesn't have a community page", sid.Steam3())
+}
+
+// ParseProfileURL accepts /profiles/ and /gid/ links with either the 64-bit or the Steam3 ID in them.
+// Custom /id/ links return ErrVanityURL.
+func ParseProfileURL(profileURL string) (*SteamID, error) {
+	mat := profileURLRegex.FindStringSubmatch(profileURL)
+	if mat == nil {
+		return nil, ErrMalformed
+	}
+	if mat[1] == "id" {
+		return nil, ErrVanityURL
+	}
+	if !steamID64Regex.MatchString(mat[2]) && !strings.HasPrefix(mat[2], "[") {
+		return nil, ErrMalformed
+	}
+	return parseSteamID(mat[2])
+}
+
+// Invite codes, friend codes and profile URLs, which all look nothing like the other notations
+func parseSteamIDLink(input string) (*SteamID, error) {
+	if sid, err := ParseProfileURL(input); !errors.Is(err, ErrMalformed) {
+		return sid, err
+	}
+	if sid, err := ParseInviteCode(input, UniversePublic); !errors.Is(err, ErrMalformed) {
+		return sid, err
+	}
+	return ParseCSFriendCode(input, UniversePublic)
+}
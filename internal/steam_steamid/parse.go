@@ -0,0 +1,115 @@
+package steam_steamid
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// What went wrong in ParseSteamID, check for these with errors.Is
+var (
+	ErrMalformed         = errors.New("not a SteamID")
+	ErrUniverseRange     = errors.New("universe out of range")
+	ErrTypeRange         = errors.New("type out of range")
+	ErrInstanceOverflow  = errors.New("instance doesn't fit in 20 bits")
+	ErrInstanceRange     = errors.New("instance not allowed for this type")
+	ErrAccountIDOverflow = errors.New("account ID doesn't fit in 32 bits")
+	ErrAccountIDMissing  = errors.New("account ID can't be 0 for this type")
+)
+
+// ParseError is returned by ParseSteamID and wraps one of the Err* values above
+type ParseError struct {
+	Input string
+	Err   error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("can't parse SteamID %q: %v", e.Input, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	strictSteam2Regex = regexp.MustCompile(`^STEAM_([0-9]):([01]):([0-9]+)$`)
+	strictSteam3Regex = regexp.MustCompile(`^\[([a-zA-Z]):([0-9]+):([0-9]+)(?::([0-9]+))?\]$`)
+	steamID64Regex    = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// ParseSteamID is the strict version of NewSteamID.
+// It accepts the same notations, including invite codes, CS friend codes and profile URLs,
+// but rejects anything that doesn't fit or that IsValid would reject.
+func ParseSteamID(input string) (*SteamID, error) {
+	sid, err := parseSteamID(input)
+	if err == nil {
+		err = sid.Validate()
+	}
+	if err != nil {
+		return nil, &ParseError{Input: input, Err: err}
+	}
+	return sid, nil
+}
+
+func parseSteamID(input string) (*SteamID, error) {
+	if mat := strictSteam2Regex.FindStringSubmatch(input); mat != nil {
+		universeNumber, _ := strconv.Atoi(mat[1])
+		universe := Universe(universeNumber)
+		if universe == UniverseInvalid {
+			universe = UniversePublic
+		}
+		accountIdLowBit, _ := strconv.ParseUint(mat[2], 10, 32)
+		accountIdHighBits, err := strconv.ParseUint(mat[3], 10, 32)
+		if err != nil || accountIdHighBits > (AccountIDMask-accountIdLowBit)/2 {
+			return nil, ErrAccountIDOverflow
+		}
+		return NewSteamIDFromParts(universe, TypeIndividual, InstanceDesktop, uint32(accountIdHighBits*2+accountIdLowBit)), nil
+	}
+
+	if mat := strictSteam3Regex.FindStringSubmatch(input); mat != nil {
+		universe, err := strconv.ParseUint(mat[2], 10, 8)
+		if err != nil {
+			return nil, ErrUniverseRange
+		}
+		accountId, err := strconv.ParseUint(mat[3], 10, 32)
+		if err != nil {
+			return nil, ErrAccountIDOverflow
+		}
+		instance := uint64(InstanceAll)
+		if mat[4] != "" {
+			instance, err = strconv.ParseUint(mat[4], 10, 20)
+			if err != nil {
+				return nil, ErrInstanceOverflow
+			}
+		} else if mat[1] == "U" {
+			instance = uint64(InstanceDesktop)
+		}
+
+		accountType := TypeChat
+		switch mat[1] {
+		case "c":
+			instance |= uint64(ChatInstanceClan)
+		case "L":
+			instance |= uint64(ChatInstanceLobby)
+		case "i", "I":
+			return nil, ErrTypeRange
+		default:
+			var found bool
+			if accountType, found = typesByChar[mat[1]]; !found {
+				return nil, ErrMalformed
+			}
+		}
+		return NewSteamIDFromParts(Universe(universe), accountType, Instance(instance), uint32(accountId)), nil
+	}
+
+	if steamID64Regex.MatchString(input) {
+		steamId64, err := strconv.ParseUint(input, 10, 64)
+		if err != nil {
+			return nil, ErrMalformed
+		}
+		return NewSteamIDFrom64(steamId64), nil
+	}
+
+	return parseSteamIDLink(input)
+}
@@ -1,8 +1,10 @@
 package steam_steamid
 
-// TODO this is blindly ported from the python version and is untested, there are probably bugs
+// Ported from the python version, steamid_test.go covers round trips between the notations
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"regexp"
 	"strconv"
@@ -10,69 +12,16 @@ import (
 
 // SteamID struct represents a Steam ID
 type SteamID struct {
-	Universe  int
-	Type      int
-	Instance  int
+	Universe  Universe
+	Type      AccountType
+	Instance  Instance
 	AccountID uint32
 }
 
-// Constants for SteamID Universe, Type, and Instance
-const (
-	UniverseInvalid = iota
-	UniversePublic
-	UniverseBeta
-	UniverseInterval
-	UniverseDev
-)
-
-const (
-	TypeInvalid = iota
-	TypeIndividual
-	TypeMultiseat
-	TypeGameServer
-	TypeAnonGameServer
-	TypePending
-	TypeContentServer
-	TypeClan
-	TypeChat
-	TypeP2PSuperSeeder
-	TypeAnonUser
-)
-
-const (
-	InstanceAll = iota
-	InstanceDesktop
-	InstanceConsole
-	InstanceWeb = 4
-)
-
-const (
-	AccountIDMask       = 0xFFFFFFFF
-	AccountInstanceMask = 0x000FFFFF
-)
-
-// ChatInstanceFlags maps different chat instances
-var ChatInstanceFlags = map[string]int{
-	"Clan":     (AccountInstanceMask + 1) >> 1,
-	"Lobby":    (AccountInstanceMask + 1) >> 2,
-	"MMSLobby": (AccountInstanceMask + 1) >> 3,
-}
-
-// TypeChars maps Steam ID types to corresponding characters
-var TypeChars = map[int]string{
-	TypeInvalid:        "I",
-	TypeIndividual:     "U",
-	TypeMultiseat:      "M",
-	TypeGameServer:     "G",
-	TypeAnonGameServer: "A",
-	TypePending:        "P",
-	TypeContentServer:  "C",
-	TypeClan:           "g",
-	TypeChat:           "T",
-	TypeAnonUser:       "a",
-}
-
-// NewSteamID initializes a new SteamID based on input
+// NewSteamID initializes a new SteamID from any of the notations: 64-bit, Steam2, Steam3,
+// invite code or link, CS friend code and profile URL.
+// It is lenient for compatibility: an empty string gives the zero ID and numbers that don't fit wrap around.
+// Use ParseSteamID to reject those.
 func NewSteamID(input string) (*SteamID, error) {
 	sid := &SteamID{
 		Universe: UniverseInvalid,
@@ -89,10 +38,10 @@ func NewSteamID(input string) (*SteamID, error) {
 	reg3 := regexp.MustCompile(`^\[([a-zA-Z]):([0-5]):([0-9]+)(:[0-9]+)?\]`)
 	if mat := reg.FindStringSubmatch(input); mat != nil {
 		universe, _ := strconv.Atoi(mat[1])
-		if universe == 0 {
-			universe = UniversePublic
+		sid.Universe = Universe(universe)
+		if sid.Universe == UniverseInvalid {
+			sid.Universe = UniversePublic
 		}
-		sid.Universe = universe
 		sid.Type = TypeIndividual
 		sid.Instance = InstanceDesktop
 		accountID, _ := strconv.Atoi(mat[3])
@@ -100,7 +49,8 @@ func NewSteamID(input string) (*SteamID, error) {
 		sid.AccountID = uint32(accountID*2 + accountIdMultiplier)
 
 	} else if mat3 := reg3.FindStringSubmatch(input); mat3 != nil {
-		sid.Universe, _ = strconv.Atoi(mat3[2])
+		universe, _ := strconv.Atoi(mat3[2])
+		sid.Universe = Universe(universe)
 
 		accountID, _ := strconv.Atoi(mat3[3])
 		sid.AccountID = uint32(accountID)
@@ -108,45 +58,58 @@ func NewSteamID(input string) (*SteamID, error) {
 		typeChar := mat3[1]
 
 		if mat3[4] != "" {
-			sid.Instance, _ = strconv.Atoi(mat3[4][1:])
+			instance, _ := strconv.Atoi(mat3[4][1:])
+			sid.Instance = Instance(instance)
 		} else if typeChar == "U" {
 			sid.Instance = InstanceDesktop
 		}
 
 		switch typeChar {
 		case "c":
-			sid.Instance = ChatInstanceFlags["Clan"]
+			sid.Instance = Instance(ChatInstanceClan)
 			sid.Type = TypeChat
 		case "L":
-			sid.Instance = ChatInstanceFlags["Lobby"]
+			sid.Instance = Instance(ChatInstanceLobby)
 			sid.Type = TypeChat
 		default:
-			sid.Type = getTypeFromChar(typeChar)
+			sid.Type = typesByChar[typeChar]
 		}
 	} else {
 		inputVal, err := strconv.ParseUint(input, 10, 64)
 		if err != nil {
-			return nil, fmt.Errorf("unknown ID: %s", input)
+			linkSid, linkErr := parseSteamIDLink(input)
+			if errors.Is(linkErr, ErrVanityURL) {
+				return nil, linkErr
+			} else if linkErr != nil {
+				return nil, fmt.Errorf("unknown ID: %s", input)
+			}
+			return linkSid, nil
 		}
-
-		// Bitwise operations to extract account ID, instance, type, and universe
-		sid.AccountID = uint32(inputVal & AccountIDMask)
-		sid.Instance = int((inputVal >> 32) & 0xFFFFF)
-		sid.Type = int((inputVal >> 52) & 0xF)
-		sid.Universe = int((inputVal >> 56) & 0xFF)
+		sid = NewSteamIDFrom64(inputVal)
 	}
 
 	return sid, nil
 }
 
-// getTypeFromChar retrieves SteamID Type from the character representation
-func getTypeFromChar(typeChar string) int {
-	for typ, char := range TypeChars {
-		if char == typeChar {
-			return typ
-		}
+// NewSteamIDFrom64 splits a 64-bit ID into its parts
+func NewSteamIDFrom64(steamId64 uint64) *SteamID {
+	return &SteamID{
+		AccountID: uint32(steamId64 & AccountIDMask),
+		Instance:  Instance((steamId64 >> 32) & AccountInstanceMask),
+		Type:      AccountType((steamId64 >> 52) & 0xF),
+		Universe:  Universe((steamId64 >> 56) & 0xFF),
+	}
+}
+
+// NewSteamIDFromParts builds a SteamID without going through a string, e.g.
+// NewSteamIDFromParts(UniversePublic, TypeIndividual, InstanceDesktop, accountID) for a normal user
+func NewSteamIDFromParts(universe Universe, accountType AccountType, instance Instance, accountID uint32) *SteamID {
+	return &SteamID{
+		Universe:  universe,
+		Type:      accountType,
+		Instance:  instance,
+		AccountID: accountID,
 	}
-	return TypeInvalid
 }
 
 // Steam2 renders Steam2 ID
@@ -163,14 +126,15 @@ func (sid *SteamID) Steam2(newerFormat bool) (string, error) {
 
 // Steam3 renders Steam3 ID
 func (sid *SteamID) Steam3() string {
-	typeChar := TypeChars[sid.Type]
+	typeChar := sid.Type.Char()
 	if typeChar == "" {
 		typeChar = "i"
 	}
 
-	if sid.Instance&ChatInstanceFlags["Clan"] != 0 {
+	// Only chats use the instance flags, other types can have those bits set in their instance
+	if sid.ChatFlags().Has(ChatInstanceClan) {
 		typeChar = "c"
-	} else if sid.Instance&ChatInstanceFlags["Lobby"] != 0 {
+	} else if sid.ChatFlags().Has(ChatInstanceLobby) {
 		typeChar = "L"
 	}
 
@@ -183,22 +147,82 @@ func (sid *SteamID) Steam3() string {
 	return fmt.Sprintf("[%s:%d:%d]", typeChar, sid.Universe, sid.AccountID)
 }
 
-// IsValid validates the SteamID
+// IsValid is true for exactly the IDs ParseSteamID accepts
 func (sid *SteamID) IsValid() bool {
-	if sid.Type <= TypeInvalid || sid.Type > TypeAnonUser {
-		return false
+	return sid.Validate() == nil
+}
+
+// Validate explains why IsValid is false, with the same errors ParseSteamID wraps
+func (sid *SteamID) Validate() error {
+	if !sid.Universe.IsValid() {
+		return ErrUniverseRange
 	}
-	if sid.Universe <= UniverseInvalid || sid.Universe > UniverseDev {
-		return false
+	if !sid.Type.IsValid() {
+		return ErrTypeRange
 	}
-	if sid.Type == TypeIndividual && (sid.AccountID == 0 || sid.Instance > InstanceWeb) {
-		return false
+	if !sid.Instance.IsValid() {
+		return ErrInstanceOverflow
 	}
-	if sid.Type == TypeClan && (sid.AccountID == 0 || sid.Instance != InstanceAll) {
-		return false
+	switch sid.Type {
+	case TypeIndividual:
+		if sid.AccountID == 0 {
+			return ErrAccountIDMissing
+		}
+		if sid.Instance > InstanceWeb {
+			return ErrInstanceRange
+		}
+	case TypeClan:
+		if sid.AccountID == 0 {
+			return ErrAccountIDMissing
+		}
+		if sid.Instance != InstanceAll {
+			return ErrInstanceRange
+		}
+	case TypeGameServer:
+		if sid.AccountID == 0 {
+			return ErrAccountIDMissing
+		}
+	}
+	return nil
+}
+
+// SteamID64 packs the ID back into the 64-bit form Steam uses in files and URLs
+func (sid SteamID) SteamID64() uint64 {
+	return uint64(sid.Universe&0xFF)<<56 |
+		uint64(sid.Type&0xF)<<52 |
+		uint64(sid.Instance&AccountInstanceMask)<<32 |
+		uint64(sid.AccountID)
+}
+
+// String renders the 64-bit ID, same as the python version
+func (sid SteamID) String() string {
+	return strconv.FormatUint(sid.SteamID64(), 10)
+}
+
+// MarshalText uses the 64-bit ID, so JSON gets a string that doesn't lose precision in JavaScript
+func (sid SteamID) MarshalText() ([]byte, error) {
+	return []byte(sid.String()), nil
+}
+
+// UnmarshalText accepts anything NewSteamID does
+func (sid *SteamID) UnmarshalText(text []byte) error {
+	parsed, err := NewSteamID(string(text))
+	if err != nil {
+		return err
 	}
-	if sid.Type == TypeGameServer && sid.AccountID == 0 {
-		return false
+	*sid = *parsed
+	return nil
+}
+
+// UnmarshalJSON accepts the 64-bit ID as a bare number too, which is how most web APIs send it
+func (sid *SteamID) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		var number json.Number
+		if err := json.Unmarshal(data, &number); err != nil {
+			return fmt.Errorf("SteamID must be a string or a number: %w", err)
+		}
+		text = number.String()
 	}
-	return true
+	return sid.UnmarshalText([]byte(text))
 }
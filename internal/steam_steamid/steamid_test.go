@@ -0,0 +1,350 @@
+package steam_steamid
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		steam64 uint64
+		steam2  string
+		steam3  string
+	}{
+		{"individual", 76561197960287930, "STEAM_0:0:11101", "[U:1:22202]"},
+		{"individual odd account", 76561197960265729, "STEAM_0:1:0", "[U:1:1]"},
+		{"individual max account", 76561202255233023, "STEAM_0:1:2147483647", "[U:1:4294967295]"},
+		{"individual web instance", 76561210845189818, "STEAM_0:0:11101", "[U:1:22202:4]"},
+		{"individual beta universe", 148618791998215866, "STEAM_2:0:11101", "[U:2:22202]"},
+		{"clan", 103582791429521412, "", "[g:1:4]"},
+		{"game server", 85568392920040658, "", "[G:1:1234]"},
+		{"anonymous game server", 90072580957929595, "", "[A:1:123:137]"},
+		{"multiseat", 81064930731622405, "", "[M:1:5:32]"},
+		{"clan chat", 110338190870577164, "", "[c:1:12]"},
+		{"lobby chat", 109212290963734540, "", "[L:1:12]"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, input := range []string{NewSteamIDFrom64(test.steam64).String(), test.steam3} {
+				sid, err := NewSteamID(input)
+				if err != nil {
+					t.Fatalf("NewSteamID(%q): %v", input, err)
+				}
+				if got := sid.SteamID64(); got != test.steam64 {
+					t.Errorf("NewSteamID(%q).SteamID64() = %v, want %v", input, got, test.steam64)
+				}
+				if got := sid.Steam3(); got != test.steam3 {
+					t.Errorf("NewSteamID(%q).Steam3() = %v, want %v", input, got, test.steam3)
+				}
+				got, err := sid.Steam2(false)
+				if test.steam2 == "" {
+					if err == nil {
+						t.Errorf("NewSteamID(%q).Steam2() = %v, want an error for a non-individual ID", input, got)
+					}
+				} else if err != nil || got != test.steam2 {
+					t.Errorf("NewSteamID(%q).Steam2() = %v, %v, want %v", input, got, err, test.steam2)
+				}
+			}
+			// Steam2 has no instance so it can only round trip to itself
+			if test.steam2 != "" {
+				sid, err := NewSteamID(test.steam2)
+				if err != nil {
+					t.Fatalf("NewSteamID(%q): %v", test.steam2, err)
+				}
+				if got, err := sid.Steam2(false); err != nil || got != test.steam2 {
+					t.Errorf("NewSteamID(%q).Steam2() = %v, %v, want %v", test.steam2, got, err, test.steam2)
+				}
+			}
+		})
+	}
+}
+
+// Parsing Steam2 always gives the desktop instance
+func TestSteam2DefaultsToDesktop(t *testing.T) {
+	sid, err := NewSteamID("STEAM_0:0:11101")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := uint64(76561197960287930); sid.SteamID64() != want {
+		t.Errorf("SteamID64() = %v, want %v", sid.SteamID64(), want)
+	}
+}
+
+func TestNewSteamIDFromParts(t *testing.T) {
+	sid := NewSteamIDFromParts(UniversePublic, TypeIndividual, InstanceDesktop, 22202)
+	if want := uint64(76561197960287930); sid.SteamID64() != want {
+		t.Errorf("SteamID64() = %v, want %v", sid.SteamID64(), want)
+	}
+	if *sid != *NewSteamIDFrom64(sid.SteamID64()) {
+		t.Errorf("NewSteamIDFrom64(%v) = %+v, want %+v", sid.SteamID64(), NewSteamIDFrom64(sid.SteamID64()), sid)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	type user struct {
+		SteamID SteamID
+	}
+	original := user{SteamID: *NewSteamIDFrom64(76561197960287930)}
+	encoded, err := json.Marshal(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"SteamID":"76561197960287930"}`; string(encoded) != want {
+		t.Errorf("json.Marshal() = %s, want %s", encoded, want)
+	}
+
+	for _, input := range []string{
+		`{"SteamID":"76561197960287930"}`,
+		`{"SteamID":76561197960287930}`,
+		`{"SteamID":"[U:1:22202]"}`,
+		`{"SteamID":"STEAM_0:0:11101"}`,
+	} {
+		var decoded user
+		if err := json.Unmarshal([]byte(input), &decoded); err != nil {
+			t.Errorf("json.Unmarshal(%s): %v", input, err)
+			continue
+		}
+		if decoded != original {
+			t.Errorf("json.Unmarshal(%s) = %+v, want %+v", input, decoded, original)
+		}
+	}
+
+	var decoded user
+	if err := json.Unmarshal([]byte(`{"SteamID":true}`), &decoded); err == nil {
+		t.Error("json.Unmarshal should fail for a bool")
+	}
+}
+
+func TestParseSteamID(t *testing.T) {
+	tests := []struct {
+		input string
+		want  uint64
+		err   error
+	}{
+		{"76561197960287930", 76561197960287930, nil},
+		{"STEAM_0:0:11101", 76561197960287930, nil},
+		{"STEAM_1:0:11101", 76561197960287930, nil},
+		{"[U:1:22202]", 76561197960287930, nil},
+		{"[g:1:4]", 103582791429521412, nil},
+		{"", 0, ErrMalformed},
+		{"garbage", 0, ErrMalformed},
+		{"[U:1:22202]trailing", 0, ErrMalformed},
+		{"[X:1:22202]", 0, ErrMalformed},
+		{"-76561197960287930", 0, ErrMalformed},
+		{"99999999999999999999", 0, ErrMalformed},
+		{"0", 0, ErrUniverseRange},
+		{"STEAM_5:0:11101", 0, ErrUniverseRange},
+		{"[U:0:22202]", 0, ErrUniverseRange},
+		{"[U:256:22202]", 0, ErrUniverseRange},
+		{"[I:1:22202]", 0, ErrTypeRange},
+		{"[U:1:22202:1048576]", 0, ErrInstanceOverflow},
+		{"[U:1:22202:5]", 0, ErrInstanceRange},
+		{"[g:1:4:1]", 0, ErrInstanceRange},
+		{"[U:1:4294967296]", 0, ErrAccountIDOverflow},
+		{"STEAM_0:0:2147483648", 0, ErrAccountIDOverflow},
+		{"STEAM_0:1:2147483647", 76561202255233023, nil},
+		{"STEAM_0:0:0", 0, ErrAccountIDMissing},
+		{"[G:1:0]", 0, ErrAccountIDMissing},
+	}
+	for _, test := range tests {
+		sid, err := ParseSteamID(test.input)
+		if test.err != nil {
+			var parseErr *ParseError
+			if !errors.Is(err, test.err) || !errors.As(err, &parseErr) || parseErr.Input != test.input {
+				t.Errorf("ParseSteamID(%q) = %v, want a ParseError wrapping %v", test.input, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseSteamID(%q): %v", test.input, err)
+			continue
+		}
+		if sid.SteamID64() != test.want {
+			t.Errorf("ParseSteamID(%q) = %v, want %v", test.input, sid, test.want)
+		}
+	}
+}
+
+// Anything ParseSteamID accepts has to survive being rendered and parsed again
+func FuzzParseSteamID(f *testing.F) {
+	for _, seed := range []string{
+		"76561197960287930", "STEAM_0:1:0", "[U:1:22202:4]", "[g:1:4]", "[A:1:123:137]",
+		"[c:1:12]", "[L:1:12]", "[M:1:5:32]", "[T:1:12]", "[a:4:1]", "", "STEAM_0:0:2147483648",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		// The lenient parser can't be trusted with the result but it mustn't panic either
+		NewSteamID(input)
+
+		sid, err := ParseSteamID(input)
+		if err != nil {
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("ParseSteamID(%q) returned %T, want *ParseError", input, err)
+			}
+			return
+		}
+		if !sid.IsValid() {
+			t.Fatalf("ParseSteamID(%q) = %+v which isn't valid", input, sid)
+		}
+
+		reparsed, err := ParseSteamID(sid.String())
+		if err != nil || *reparsed != *sid {
+			t.Fatalf("ParseSteamID(%q) = %+v, %v, want %+v", sid.String(), reparsed, err, sid)
+		}
+
+		// Steam3 drops the instance for some types so it only has to render the same way again.
+		// P2P super seeders don't have a Steam3 letter at all.
+		if sid.Type != TypeP2PSuperSeeder {
+			steam3 := sid.Steam3()
+			reparsed, err := ParseSteamID(steam3)
+			if err != nil || reparsed.Steam3() != steam3 {
+				t.Fatalf("ParseSteamID(%q) = %+v, %v, want it to render as %v", steam3, reparsed, err, steam3)
+			}
+		}
+
+		if sid.Type == TypeIndividual {
+			steam2, err := sid.Steam2(false)
+			if err != nil {
+				t.Fatalf("%+v.Steam2(): %v", sid, err)
+			}
+			reparsed, err := ParseSteamID(steam2)
+			if err != nil || reparsed.AccountID != sid.AccountID || reparsed.Universe != sid.Universe {
+				t.Fatalf("ParseSteamID(%q) = %+v, %v, want account %v in universe %v", steam2, reparsed, err, sid.AccountID, sid.Universe)
+			}
+		}
+	})
+}
+
+// IsValid and ParseSteamID have to agree on every 64-bit ID
+func FuzzIsValid(f *testing.F) {
+	for _, seed := range []uint64{0, 76561197960287930, 103582791429521412, 90072580957929595, 1 << 63} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, steamId64 uint64) {
+		sid := NewSteamIDFrom64(steamId64)
+		_, err := ParseSteamID(sid.String())
+		if sid.IsValid() != (err == nil) {
+			t.Fatalf("%v: IsValid() = %v but ParseSteamID returned %v", steamId64, sid.IsValid(), err)
+		}
+	})
+}
+
+func TestLinks(t *testing.T) {
+	tests := []struct {
+		steam64      uint64
+		inviteCode   string
+		csFriendCode string
+		profileURL   string
+	}{
+		{76561197960287930, "hj-qp", "SUCVS-FADA", "https://steamcommunity.com/profiles/76561197960287930"},
+		{76561197960389184, "cv-dgb", "", "https://steamcommunity.com/profiles/76561197960389184"},
+		{76561197960265729, "c", "AJJJS-ABAA", "https://steamcommunity.com/profiles/76561197960265729"},
+		{76561202255233023, "wwww-wwww", "", "https://steamcommunity.com/profiles/76561202255233023"},
+	}
+	for _, test := range tests {
+		sid := NewSteamIDFrom64(test.steam64)
+		if got, err := sid.InviteCode(); err != nil || got != test.inviteCode {
+			t.Errorf("%v.InviteCode() = %v, %v, want %v", test.steam64, got, err, test.inviteCode)
+		}
+		if got, err := sid.InviteURL(); err != nil || got != InviteURLPrefix+test.inviteCode {
+			t.Errorf("%v.InviteURL() = %v, %v, want %v", test.steam64, got, err, InviteURLPrefix+test.inviteCode)
+		}
+		csFriendCode, err := sid.CSFriendCode()
+		if err != nil || (test.csFriendCode != "" && csFriendCode != test.csFriendCode) {
+			t.Errorf("%v.CSFriendCode() = %v, %v, want %v", test.steam64, csFriendCode, err, test.csFriendCode)
+		}
+		if got, err := sid.ProfileURL(); err != nil || got != test.profileURL {
+			t.Errorf("%v.ProfileURL() = %v, %v, want %v", test.steam64, got, err, test.profileURL)
+		}
+
+		inputs := []string{
+			InviteURLPrefix + test.inviteCode,
+			"s.team/p/" + test.inviteCode,
+			csFriendCode,
+			test.profileURL,
+			test.profileURL + "/",
+			"steamcommunity.com/profiles/" + sid.Steam3() + "/games?tab=all",
+		}
+		// Codes too short for a dash only work as links
+		if strings.Contains(test.inviteCode, "-") {
+			inputs = append(inputs, test.inviteCode)
+		}
+		for _, input := range inputs {
+			parsed, err := ParseSteamID(input)
+			if err != nil || parsed.SteamID64() != test.steam64 {
+				t.Errorf("ParseSteamID(%q) = %v, %v, want %v", input, parsed, err, test.steam64)
+			}
+			parsed, err = NewSteamID(input)
+			if err != nil || parsed.SteamID64() != test.steam64 {
+				t.Errorf("NewSteamID(%q) = %v, %v, want %v", input, parsed, err, test.steam64)
+			}
+		}
+	}
+
+	clan := NewSteamIDFrom64(103582791429521412)
+	if got, err := clan.ProfileURL(); err != nil || got != "https://steamcommunity.com/gid/103582791429521412" {
+		t.Errorf("clan ProfileURL() = %v, %v", got, err)
+	}
+	if _, err := clan.InviteCode(); err == nil {
+		t.Error("clan InviteCode() should fail")
+	}
+	if _, err := clan.CSFriendCode(); err == nil {
+		t.Error("clan CSFriendCode() should fail")
+	}
+
+	for input, want := range map[string]error{
+		"https://steamcommunity.com/id/gabelogannewell": ErrVanityURL,
+		"https://steamcommunity.com/profiles/garbage":   ErrMalformed,
+		"https://s.team/p/cv-dga":                       ErrMalformed,
+		"SUCVS-FADB":                                    ErrMalformed,
+		"bbbbbbbbbbbbbbbbb":                             ErrMalformed,
+		"c":                                             ErrMalformed,
+		"dj":                                            ErrMalformed,
+		"bcdf":                                          ErrMalformed,
+		"bc-df":                                         ErrMalformed,
+		"cvd-gb":                                        ErrMalformed,
+	} {
+		if _, err := ParseSteamID(input); !errors.Is(err, want) {
+			t.Errorf("ParseSteamID(%q) = %v, want %v", input, err, want)
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		steam3 string
+		want   string
+	}{
+		{"[U:1:22202]", "Individual 22202 (Public universe, Desktop instance)"},
+		{"[U:2:22202:4]", "Individual 22202 (Beta universe, Web instance)"},
+		{"[g:1:4]", "Clan 4 (Public universe, All instance)"},
+		{"[A:1:123:137]", "AnonGameServer 123 (Public universe, Instance(137) instance)"},
+		{"[c:1:12]", "Chat 12 (Public universe, Clan)"},
+		{"[L:1:12]", "Chat 12 (Public universe, Lobby)"},
+		{"[T:1:12]", "Chat 12 (Public universe, None)"},
+	}
+	for _, test := range tests {
+		sid, err := ParseSteamID(test.steam3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := sid.Describe(); got != test.want {
+			t.Errorf("ParseSteamID(%q).Describe() = %v, want %v", test.steam3, got, test.want)
+		}
+	}
+
+	if got := (ChatInstanceClan | ChatInstanceMMSLobby).String(); got != "Clan|MMSLobby" {
+		t.Errorf("ChatInstanceFlags.String() = %v, want Clan|MMSLobby", got)
+	}
+	if NewSteamIDFromParts(UniversePublic, TypeMultiseat, Instance(ChatInstanceClan), 1).ChatFlags() != 0 {
+		t.Error("ChatFlags() should be 0 for anything but chats")
+	}
+	if Universe(5).IsValid() || AccountType(11).IsValid() || Instance(-1).IsValid() || Instance(AccountInstanceMask+1).IsValid() {
+		t.Error("IsValid() should be false for values Steam doesn't define")
+	}
+}
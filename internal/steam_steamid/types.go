@@ -0,0 +1,182 @@
+package steam_steamid
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Universe is which Steam a SteamID belongs to, nearly everything is UniversePublic
+type Universe int
+
+const (
+	UniverseInvalid Universe = iota
+	UniversePublic
+	UniverseBeta
+	UniverseInternal
+	UniverseDev
+)
+
+// Deprecated: misspelling of UniverseInternal
+const UniverseInterval = UniverseInternal
+
+var universeNames = [...]string{"Invalid", "Public", "Beta", "Internal", "Dev"}
+
+func (u Universe) String() string {
+	if u < 0 || int(u) >= len(universeNames) {
+		return fmt.Sprintf("Universe(%d)", int(u))
+	}
+	return universeNames[u]
+}
+
+// IsValid is false for UniverseInvalid and anything Steam doesn't define
+func (u Universe) IsValid() bool {
+	return u > UniverseInvalid && u <= UniverseDev
+}
+
+// AccountType is what kind of account a SteamID is for
+type AccountType int
+
+const (
+	TypeInvalid AccountType = iota
+	TypeIndividual
+	TypeMultiseat
+	TypeGameServer
+	TypeAnonGameServer
+	TypePending
+	TypeContentServer
+	TypeClan
+	TypeChat
+	TypeP2PSuperSeeder
+	TypeAnonUser
+)
+
+var accountTypeNames = [...]string{
+	"Invalid", "Individual", "Multiseat", "GameServer", "AnonGameServer", "Pending",
+	"ContentServer", "Clan", "Chat", "P2PSuperSeeder", "AnonUser",
+}
+
+func (t AccountType) String() string {
+	if t < 0 || int(t) >= len(accountTypeNames) {
+		return fmt.Sprintf("AccountType(%d)", int(t))
+	}
+	return accountTypeNames[t]
+}
+
+// IsValid is false for TypeInvalid and anything Steam doesn't define
+func (t AccountType) IsValid() bool {
+	return t > TypeInvalid && t <= TypeAnonUser
+}
+
+// Char is the letter used in Steam3 IDs, or "" for types that don't have one
+func (t AccountType) Char() string {
+	return TypeChars[t]
+}
+
+// TypeChars maps Steam ID types to corresponding characters
+var TypeChars = map[AccountType]string{
+	TypeInvalid:        "I",
+	TypeIndividual:     "U",
+	TypeMultiseat:      "M",
+	TypeGameServer:     "G",
+	TypeAnonGameServer: "A",
+	TypePending:        "P",
+	TypeContentServer:  "C",
+	TypeClan:           "g",
+	TypeChat:           "T",
+	TypeAnonUser:       "a",
+}
+
+// The reverse of TypeChars for parsing
+var typesByChar = func() map[string]AccountType {
+	typesByChar := make(map[string]AccountType, len(TypeChars))
+	for accountType, char := range TypeChars {
+		typesByChar[char] = accountType
+	}
+	return typesByChar
+}()
+
+// Instance tells apart sessions of the same account. For chats the top bits are ChatInstanceFlags instead.
+type Instance int
+
+const (
+	InstanceAll Instance = iota
+	InstanceDesktop
+	InstanceConsole
+	InstanceWeb Instance = 4
+)
+
+func (i Instance) String() string {
+	switch i {
+	case InstanceAll:
+		return "All"
+	case InstanceDesktop:
+		return "Desktop"
+	case InstanceConsole:
+		return "Console"
+	case InstanceWeb:
+		return "Web"
+	}
+	return fmt.Sprintf("Instance(%d)", int(i))
+}
+
+// IsValid is true if the instance fits in the 20 bits a SteamID has for it
+func (i Instance) IsValid() bool {
+	return i >= 0 && i <= AccountInstanceMask
+}
+
+const (
+	AccountIDMask       = 0xFFFFFFFF
+	AccountInstanceMask = 0x000FFFFF
+)
+
+// ChatInstanceFlags are the top bits of a chat's instance that say what kind of chat it is
+type ChatInstanceFlags int
+
+const (
+	ChatInstanceClan     ChatInstanceFlags = (AccountInstanceMask + 1) >> 1
+	ChatInstanceLobby    ChatInstanceFlags = (AccountInstanceMask + 1) >> 2
+	ChatInstanceMMSLobby ChatInstanceFlags = (AccountInstanceMask + 1) >> 3
+
+	chatInstanceFlagsMask = ChatInstanceClan | ChatInstanceLobby | ChatInstanceMMSLobby
+)
+
+func (f ChatInstanceFlags) Has(flag ChatInstanceFlags) bool {
+	return f&flag == flag
+}
+
+// Names of the set flags joined with "|", e.g. "Clan|Lobby"
+func (f ChatInstanceFlags) String() string {
+	var names []string
+	for _, flag := range []struct {
+		flag ChatInstanceFlags
+		name string
+	}{
+		{ChatInstanceClan, "Clan"},
+		{ChatInstanceLobby, "Lobby"},
+		{ChatInstanceMMSLobby, "MMSLobby"},
+	} {
+		if f.Has(flag.flag) {
+			names = append(names, flag.name)
+		}
+	}
+	if len(names) == 0 {
+		return "None"
+	}
+	return strings.Join(names, "|")
+}
+
+// ChatFlags is only meaningful for chats, every other type gets 0
+func (sid SteamID) ChatFlags() ChatInstanceFlags {
+	if sid.Type != TypeChat {
+		return 0
+	}
+	return ChatInstanceFlags(sid.Instance) & chatInstanceFlagsMask
+}
+
+// Describe spells out what the ID is for humans, e.g. "Individual 22202 (Public universe, Desktop instance)"
+func (sid SteamID) Describe() string {
+	if sid.Type == TypeChat {
+		return fmt.Sprintf("Chat %v (%v universe, %v)", sid.AccountID, sid.Universe, sid.ChatFlags())
+	}
+	return fmt.Sprintf("%v %v (%v universe, %v instance)", sid.Type, sid.AccountID, sid.Universe, sid.Instance)
+}
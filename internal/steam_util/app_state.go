@@ -0,0 +1,162 @@
+package steam_util
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Steam's EAppState bitmask, stored as StateFlags in appmanifest_*.acf
+type AppStateFlags uint32
+
+const (
+	AppStateInvalid        AppStateFlags = 0
+	AppStateUninstalled    AppStateFlags = 1 << 0
+	AppStateUpdateRequired AppStateFlags = 1 << 1
+	AppStateFullyInstalled AppStateFlags = 1 << 2
+	AppStateEncrypted      AppStateFlags = 1 << 3
+	AppStateLocked         AppStateFlags = 1 << 4
+	AppStateFilesMissing   AppStateFlags = 1 << 5
+	AppStateAppRunning     AppStateFlags = 1 << 6
+	AppStateFilesCorrupt   AppStateFlags = 1 << 7
+	AppStateUpdateRunning  AppStateFlags = 1 << 8
+	AppStateUpdatePaused   AppStateFlags = 1 << 9
+	AppStateUpdateStarted  AppStateFlags = 1 << 10
+	AppStateUninstalling   AppStateFlags = 1 << 11
+	AppStateBackupRunning  AppStateFlags = 1 << 12
+	AppStateReconfiguring  AppStateFlags = 1 << 16
+	AppStateValidating     AppStateFlags = 1 << 17
+	AppStateAddingFiles    AppStateFlags = 1 << 18
+	AppStatePreallocating  AppStateFlags = 1 << 19
+	AppStateDownloading    AppStateFlags = 1 << 20
+	AppStateStaging        AppStateFlags = 1 << 21
+	AppStateCommitting     AppStateFlags = 1 << 22
+	AppStateUpdateStopping AppStateFlags = 1 << 23
+)
+
+// Flags that mean Steam is busy with the game and will clear them on its own
+const appStateInProgressMask = AppStateUpdateRunning | AppStateUpdateStarted | AppStateUninstalling |
+	AppStateBackupRunning | AppStateReconfiguring | AppStateValidating | AppStateAddingFiles |
+	AppStatePreallocating | AppStateDownloading | AppStateStaging | AppStateCommitting | AppStateUpdateStopping
+
+var appStateDescriptions = []struct {
+	flag        AppStateFlags
+	name        string
+	explanation string
+}{
+	{AppStateUninstalled, "Uninstalled", "the game isn't installed"},
+	{AppStateUpdateRequired, "UpdateRequired", "an update is required before the game can be played"},
+	{AppStateFullyInstalled, "FullyInstalled", "the game is fully installed"},
+	{AppStateEncrypted, "Encrypted", "the game files are encrypted (preloaded)"},
+	{AppStateLocked, "Locked", "the game files are locked by Steam"},
+	{AppStateFilesMissing, "FilesMissing", "some game files are missing"},
+	{AppStateAppRunning, "AppRunning", "the game is running"},
+	{AppStateFilesCorrupt, "FilesCorrupt", "some game files are corrupt"},
+	{AppStateUpdateRunning, "UpdateRunning", "an update is running"},
+	{AppStateUpdatePaused, "UpdatePaused", "an update is paused"},
+	{AppStateUpdateStarted, "UpdateStarted", "an update has started"},
+	{AppStateUninstalling, "Uninstalling", "the game is being uninstalled"},
+	{AppStateBackupRunning, "BackupRunning", "a backup is running"},
+	{AppStateReconfiguring, "Reconfiguring", "the game is being reconfigured"},
+	{AppStateValidating, "Validating", "the game files are being validated"},
+	{AppStateAddingFiles, "AddingFiles", "files are being added"},
+	{AppStatePreallocating, "Preallocating", "disk space is being preallocated"},
+	{AppStateDownloading, "Downloading", "an update is downloading"},
+	{AppStateStaging, "Staging", "an update is being staged"},
+	{AppStateCommitting, "Committing", "an update is being committed"},
+	{AppStateUpdateStopping, "UpdateStopping", "an update is stopping"},
+}
+
+func (f AppStateFlags) Has(flag AppStateFlags) bool {
+	return f&flag == flag
+}
+
+// Ready to patch means installed and nothing else going on
+func (f AppStateFlags) IsReady() bool {
+	return f == AppStateFullyInstalled
+}
+
+// Whether Steam is in the middle of something that will change the state without user action
+func (f AppStateFlags) InProgress() bool {
+	return f&appStateInProgressMask != 0
+}
+
+func (f AppStateFlags) String() string {
+	if f == AppStateInvalid {
+		return "Invalid"
+	}
+	var names []string
+	remaining := f
+	for _, desc := range appStateDescriptions {
+		if f.Has(desc.flag) {
+			names = append(names, desc.name)
+			remaining &^= desc.flag
+		}
+	}
+	if remaining != 0 {
+		names = append(names, fmt.Sprintf("0x%X", uint32(remaining)))
+	}
+	return strings.Join(names, "|")
+}
+
+// Human readable explanation for every flag that is set
+func (f AppStateFlags) Explain() []string {
+	if f == AppStateInvalid {
+		return []string{"Steam doesn't know the state of the game"}
+	}
+	var explanations []string
+	for _, desc := range appStateDescriptions {
+		if f.Has(desc.flag) {
+			explanations = append(explanations, desc.explanation)
+		}
+	}
+	return explanations
+}
+
+// Like StateFlags.Explain but also covers the rest of what GameIsInGoodState checks
+func ExplainGameState(manifest *VdfAppManifest) []string {
+	explanations := manifest.AppState.StateFlags.Explain()
+	if manifest.AppState.ScheduledAutoUpdate != 0 {
+		explanations = append(explanations, "an automatic update is scheduled")
+	}
+	return explanations
+}
+
+// How often WaitUntilReady re-reads the appmanifest
+var AppStatePollInterval = 2 * time.Second
+
+// Block until Steam finishes downloading, updating or validating the game, re-reading the appmanifest as it goes.
+// Returns an error straight away if the game isn't ready and Steam isn't doing anything about it.
+// onProgress is called on every poll and can be nil.
+func (g *GameInstall) WaitUntilReady(ctx context.Context, onProgress func(*DownloadProgress)) error {
+	ticker := time.NewTicker(AppStatePollInterval)
+	defer ticker.Stop()
+	for {
+		download := g.DownloadProgress()
+		if GameIsInGoodState(g.Manifest) && !download.Active() {
+			return nil
+		}
+		stateFlags := g.Manifest.AppState.StateFlags
+		if stateFlags.Has(AppStateUpdatePaused) {
+			return fmt.Errorf("The update is paused, resume it in Steam's downloads")
+		}
+		if !stateFlags.InProgress() && !download.Active() {
+			return fmt.Errorf("Game isn't ready (%v): %s", stateFlags, strings.Join(ExplainGameState(g.Manifest), ", "))
+		}
+		if onProgress != nil {
+			onProgress(download)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		var steamGameManifest VdfAppManifest
+		if err := initVdfStructFromFile(g.ManifestPath, &steamGameManifest); err != nil {
+			// Steam rewrites the file while it works so it may be briefly unreadable
+			continue
+		}
+		g.Manifest = &steamGameManifest
+	}
+}
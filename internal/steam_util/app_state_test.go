@@ -0,0 +1,105 @@
+package steam_util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAppStateFlags(t *testing.T) {
+	tests := []struct {
+		flags          AppStateFlags
+		wantString     string
+		wantReady      bool
+		wantInProgress bool
+		wantExplain    []string
+	}{
+		{AppStateInvalid, "Invalid", false, false, []string{"Steam doesn't know the state of the game"}},
+		{AppStateFullyInstalled, "FullyInstalled", true, false, []string{"the game is fully installed"}},
+		// 6 is what Steam writes while an update is waiting to be started
+		{
+			AppStateUpdateRequired | AppStateFullyInstalled, "UpdateRequired|FullyInstalled", false, false,
+			[]string{"an update is required before the game can be played", "the game is fully installed"},
+		},
+		{
+			AppStateFullyInstalled | AppStateAppRunning, "FullyInstalled|AppRunning", false, false,
+			[]string{"the game is fully installed", "the game is running"},
+		},
+		// 1026 is a download that's going on right now
+		{
+			AppStateUpdateRequired | AppStateUpdateStarted, "UpdateRequired|UpdateStarted", false, true,
+			[]string{"an update is required before the game can be played", "an update has started"},
+		},
+		{
+			AppStateUpdateRequired | AppStateUpdatePaused, "UpdateRequired|UpdatePaused", false, false,
+			[]string{"an update is required before the game can be played", "an update is paused"},
+		},
+		{
+			AppStateFullyInstalled | AppStateValidating, "FullyInstalled|Validating", false, true,
+			[]string{"the game is fully installed", "the game files are being validated"},
+		},
+		{AppStateCommitting, "Committing", false, true, []string{"an update is being committed"}},
+		{AppStateFilesCorrupt, "FilesCorrupt", false, false, []string{"some game files are corrupt"}},
+		// Bits Steam doesn't document are shown as a number instead of being dropped
+		{AppStateFullyInstalled | 1<<13 | 1<<30, "FullyInstalled|0x40002000", false, false, []string{"the game is fully installed"}},
+		{1 << 14, "0x4000", false, false, nil},
+	}
+	for _, test := range tests {
+		if got := test.flags.String(); got != test.wantString {
+			t.Errorf("AppStateFlags(%v).String() = %q, want %q", uint32(test.flags), got, test.wantString)
+		}
+		if got := test.flags.IsReady(); got != test.wantReady {
+			t.Errorf("%v.IsReady() = %v, want %v", test.flags, got, test.wantReady)
+		}
+		if got := test.flags.InProgress(); got != test.wantInProgress {
+			t.Errorf("%v.InProgress() = %v, want %v", test.flags, got, test.wantInProgress)
+		}
+		if got := test.flags.Explain(); !reflect.DeepEqual(got, test.wantExplain) {
+			t.Errorf("%v.Explain() = %q, want %q", test.flags, got, test.wantExplain)
+		}
+	}
+}
+
+func TestAppStateFlagsHas(t *testing.T) {
+	flags := AppStateUpdateRequired | AppStateFullyInstalled
+	tests := []struct {
+		flag AppStateFlags
+		want bool
+	}{
+		{AppStateFullyInstalled, true},
+		{AppStateUpdateRequired | AppStateFullyInstalled, true},
+		{AppStateAppRunning, false},
+		// Every bit of a combined flag has to be set
+		{AppStateFullyInstalled | AppStateAppRunning, false},
+		{AppStateInvalid, true},
+	}
+	for _, test := range tests {
+		if got := flags.Has(test.flag); got != test.want {
+			t.Errorf("%v.Has(%v) = %v, want %v", flags, test.flag, got, test.want)
+		}
+	}
+}
+
+func TestGameIsInGoodState(t *testing.T) {
+	tests := []struct {
+		flags               AppStateFlags
+		scheduledAutoUpdate int
+		want                bool
+		wantExplain         []string
+	}{
+		{AppStateFullyInstalled, 0, true, []string{"the game is fully installed"}},
+		{AppStateFullyInstalled, 1700000000, false, []string{"the game is fully installed", "an automatic update is scheduled"}},
+		{AppStateFullyInstalled | AppStateUpdateRequired, 0, false, []string{"an update is required before the game can be played", "the game is fully installed"}},
+		{AppStateInvalid, 0, false, []string{"Steam doesn't know the state of the game"}},
+	}
+	for _, test := range tests {
+		manifest := &VdfAppManifest{}
+		manifest.AppState.StateFlags = test.flags
+		manifest.AppState.ScheduledAutoUpdate = test.scheduledAutoUpdate
+		if got := GameIsInGoodState(manifest); got != test.want {
+			t.Errorf("GameIsInGoodState(%v, scheduled %v) = %v, want %v", test.flags, test.scheduledAutoUpdate, got, test.want)
+		}
+		if got := ExplainGameState(manifest); !reflect.DeepEqual(got, test.wantExplain) {
+			t.Errorf("ExplainGameState(%v, scheduled %v) = %q, want %q", test.flags, test.scheduledAutoUpdate, got, test.wantExplain)
+		}
+	}
+}
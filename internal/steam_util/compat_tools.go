@@ -0,0 +1,253 @@
+package steam_util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode"
+)
+
+// What a compat tool runs, which decides whether the Windows or the Linux version of a game is used
+const (
+	COMPAT_RUNTIME_WINDOWS = "windows"
+	COMPAT_RUNTIME_LINUX   = "linux"
+)
+
+// Where the effective compat tool mapping came from
+const (
+	COMPAT_SOURCE_APP         = "app"
+	COMPAT_SOURCE_LOCALCONFIG = "localconfig"
+	COMPAT_SOURCE_DEFAULT     = "default"
+)
+
+// Not read from a vdf file directly, this is the compat tool Steam will run an app with.
+// Path, DisplayName and Version are empty if the tool couldn't be found on disk.
+type CompatTool struct {
+	Name        string
+	DisplayName string
+	Source      string
+	Runtime     string
+	Path        string
+	Version     string
+}
+
+// True for Proton and anything else that runs the Windows version of a game
+func (t *CompatTool) IsWindowsRuntime() bool {
+	return t != nil && t.Runtime == COMPAT_RUNTIME_WINDOWS
+}
+
+func (t *CompatTool) String() string {
+	if t == nil {
+		return "none"
+	}
+	name := t.Name
+	if t.DisplayName != "" && t.DisplayName != t.Name {
+		name = fmt.Sprintf("%v (%v)", t.DisplayName, t.Name)
+	}
+	if t.Version != "" {
+		name += " " + t.Version
+	}
+	return name
+}
+
+// ResolveCompatTool works out which compat tool Steam will use for the app, or nil if it runs natively.
+// The first mapping found wins:
+//  1. the app's entry in config.vdf's CompatToolMapping
+//  2. the app's entry in the user's localconfig.vdf, if steamUser isn't nil
+//  3. the global default (key 0 in config.vdf), which Steam only applies to apps without a Linux version
+//
+// steamLibraries is used to find the tools Steam ships, which are installed like games. It can be nil.
+func ResolveCompatTool(steamPath string, steamUser *SteamUser, steamLibraries *VdfLibraryFolders, appId uint32, nativeLinux bool) (*CompatTool, error) {
+	steamConfig, err := GetConfig(steamPath, appId)
+	if err != nil {
+		return nil, err
+	}
+	compatToolMapping := steamConfig.InstallConfigStore.Software.Valve.Steam.CompatToolMapping
+	tool := &CompatTool{Name: compatToolMapping[appId].Name, Source: COMPAT_SOURCE_APP}
+	if tool.Name == "" && steamUser != nil {
+		if localConfig, err := GetLocalConfig(steamPath, *steamUser); err == nil {
+			tool.Name = localConfig.UserLocalConfigStore.Software.Valve.Steam.CompatToolMapping[appId].Name
+			tool.Source = COMPAT_SOURCE_LOCALCONFIG
+		}
+	}
+	if tool.Name == "" && !nativeLinux {
+		tool.Name = compatToolMapping[0].Name
+		tool.Source = COMPAT_SOURCE_DEFAULT
+	}
+	if tool.Name == "" {
+		return nil, nil
+	}
+
+	if !findCustomCompatTool(steamPath, tool) {
+		findBuiltinCompatTool(steamLibraries, tool)
+	}
+	if tool.Path != "" {
+		if tool.Runtime == "" {
+			tool.Runtime = compatToolRuntimeFromManifest(tool.Path)
+		}
+		tool.Version = compatToolVersion(tool.Path)
+	}
+	if tool.Runtime == "" {
+		tool.Runtime = compatToolRuntimeFromName(tool.Name)
+	}
+	return tool, nil
+}
+
+// Whether any of the app's launch options run on Linux without a compat tool
+func AppHasNativeLinux(appInfo *VdfAppInfo) bool {
+	for _, launch := range appInfo.Data.AppInfo.Config.Launch {
+		if launch.Config.OsList == "" || strings.Contains(launch.Config.OsList, "linux") {
+			return true
+		}
+	}
+	return false
+}
+
+// The platform name the patch manifest uses for an app run with tool, which can be nil
+func CompatTargetPlatform(tool *CompatTool) string {
+	targetPlatform := runtime.GOOS
+	if targetPlatform == "linux" && tool.IsWindowsRuntime() {
+		targetPlatform = "windows"
+	}
+	// Use matching name from python sys.platform
+	if targetPlatform == "windows" {
+		targetPlatform = "win32"
+	}
+	return targetPlatform
+}
+
+// Directories custom tools like GE-Proton get installed to, each tool in its own subdirectory
+func compatToolDirs(steamPath string) []string {
+	compatToolDirs := []string{
+		filepath.Join(steamPath, "compatibilitytools.d"),
+		"/usr/share/steam/compatibilitytools.d",
+		"/usr/local/share/steam/compatibilitytools.d",
+	}
+	if extraPaths := os.Getenv("STEAM_EXTRA_COMPAT_TOOLS_PATHS"); extraPaths != "" {
+		compatToolDirs = append(compatToolDirs, filepath.SplitList(extraPaths)...)
+	}
+	return compatToolDirs
+}
+
+// Custom tools say what they are in compatibilitytool.vdf
+func findCustomCompatTool(steamPath string, tool *CompatTool) bool {
+	for _, compatToolDir := range compatToolDirs(steamPath) {
+		entries, err := os.ReadDir(compatToolDir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			toolDir := filepath.Join(compatToolDir, entry.Name())
+			var compatibilityTool VdfCompatibilityTool
+			if err := initVdfStructFromFile(filepath.Join(toolDir, "compatibilitytool.vdf"), &compatibilityTool); err != nil {
+				continue
+			}
+			definition, found := compatibilityTool.CompatibilityTools.Compat_Tools[tool.Name]
+			if !found {
+				continue
+			}
+			tool.Path = filepath.Join(toolDir, definition.Install_Path)
+			tool.DisplayName = definition.Display_Name
+			if strings.Contains(definition.From_OsList, "windows") {
+				tool.Runtime = COMPAT_RUNTIME_WINDOWS
+			} else if definition.From_OsList != "" {
+				tool.Runtime = COMPAT_RUNTIME_LINUX
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// Tools Steam ships are installed like games and only appinfo knows their internal names,
+// so match the name against the directory names instead: proton_9 is "Proton 9.0",
+// proton_experimental is "Proton - Experimental", steamlinuxruntime_sniper is "SteamLinuxRuntime_sniper".
+func findBuiltinCompatTool(steamLibraries *VdfLibraryFolders, tool *CompatTool) bool {
+	if steamLibraries == nil {
+		return false
+	}
+	wantedName := normalizeCompatToolName(tool.Name)
+	for _, key := range sortedNumericKeys(steamLibraries.Libraryfolders) {
+		commonDir := filepath.Join(steamLibraries.Libraryfolders[key].Path, "steamapps", "common")
+		entries, err := os.ReadDir(commonDir)
+		if err != nil {
+			continue
+		}
+		bestMatch := ""
+		for _, entry := range entries {
+			dirName := normalizeCompatToolName(entry.Name())
+			if !strings.HasPrefix(dirName, wantedName) {
+				continue
+			}
+			if _, err := os.Stat(filepath.Join(commonDir, entry.Name(), "toolmanifest.vdf")); err != nil {
+				continue
+			}
+			// Prefer "Proton 9.0" over "Proton 9.0 (Beta)" and exact matches over everything
+			if bestMatch == "" || len(entry.Name()) < len(bestMatch) {
+				bestMatch = entry.Name()
+			}
+		}
+		if bestMatch != "" {
+			tool.Path = filepath.Join(commonDir, bestMatch)
+			tool.DisplayName = bestMatch
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeCompatToolName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+// Proton registers itself as the "proton" layer, the Linux runtimes as "container-runtime"
+func compatToolRuntimeFromManifest(toolPath string) string {
+	var toolManifest VdfToolManifest
+	if err := initVdfStructFromFile(filepath.Join(toolPath, "toolmanifest.vdf"), &toolManifest); err != nil {
+		return ""
+	}
+	switch toolManifest.Manifest.Compatmanager_Layer_Name {
+	case "":
+		return ""
+	case "proton":
+		return COMPAT_RUNTIME_WINDOWS
+	}
+	return COMPAT_RUNTIME_LINUX
+}
+
+// Last resort for tools we couldn't find on disk
+func compatToolRuntimeFromName(name string) string {
+	name = strings.ToLower(name)
+	if strings.HasPrefix(name, "steamlinuxruntime") {
+		return COMPAT_RUNTIME_LINUX
+	}
+	if strings.Contains(name, "proton") {
+		return COMPAT_RUNTIME_WINDOWS
+	}
+	return ""
+}
+
+// Proton keeps "<timestamp> <version>" in a file called version
+func compatToolVersion(toolPath string) string {
+	version, err := os.ReadFile(filepath.Join(toolPath, "version"))
+	if err != nil {
+		return ""
+	}
+	return lastField(string(version))
+}
+
+// The version without the timestamp, also used for the copy of the version file in a prefix's config_info
+func lastField(versionLine string) string {
+	fields := strings.Fields(versionLine)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[len(fields)-1]
+}
@@ -0,0 +1,110 @@
+package steam_util
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Steam's progress on an update, from the appmanifest and the directories it downloads into.
+// The manifest only gets rewritten every now and then, so the byte counts lag behind a bit.
+type DownloadProgress struct {
+	BytesToDownload uint64
+	BytesDownloaded uint64
+	BytesToStage    uint64
+	BytesStaged     uint64
+	StateFlags      AppStateFlags
+	// steamapps/downloading/<appid> and steamapps/temp/<appid>, empty if Steam isn't using them
+	DownloadingPath string
+	TempPath        string
+}
+
+// Whether Steam has files for the app in flight right now. Cancelled and failed updates leave files behind,
+// so the directories only count while the manifest says there's an update going on.
+func (p *DownloadProgress) Active() bool {
+	return (p.DownloadingPath != "" || p.TempPath != "") && (p.Pending() || p.StateFlags.InProgress())
+}
+
+// Whether the appmanifest says there's more to download or stage.
+// A queued update that Steam hasn't started yet is pending but not active.
+func (p *DownloadProgress) Pending() bool {
+	return p.BytesDownloaded < p.BytesToDownload || p.BytesStaged < p.BytesToStage
+}
+
+func (p *DownloadProgress) InProgress() bool {
+	return p.Active() || p.Pending()
+}
+
+// Between 0 and 1, or -1 if Steam hasn't said how much there is
+func (p *DownloadProgress) DownloadFraction() float64 {
+	return fraction(p.BytesDownloaded, p.BytesToDownload)
+}
+
+// Between 0 and 1, or -1 if Steam hasn't said how much there is
+func (p *DownloadProgress) StageFraction() float64 {
+	return fraction(p.BytesStaged, p.BytesToStage)
+}
+
+func fraction(done uint64, total uint64) float64 {
+	if total == 0 {
+		return -1
+	}
+	return min(float64(done)/float64(total), 1)
+}
+
+// DownloadProgress looks at the manifest as it was last read and at what's on disk right now.
+// It's cheap enough to poll, the directories are only listed and not measured.
+func (g *GameInstall) DownloadProgress() *DownloadProgress {
+	appState := g.Manifest.AppState
+	progress := &DownloadProgress{
+		BytesToDownload: appState.BytesToDownload,
+		BytesDownloaded: appState.BytesDownloaded,
+		BytesToStage:    appState.BytesToStage,
+		BytesStaged:     appState.BytesStaged,
+		StateFlags:      appState.StateFlags,
+	}
+	appDir := fmt.Sprintf("%v", g.AppId)
+	if downloadingPath := filepath.Join(g.LibraryPath, "steamapps", "downloading", appDir); dirHasContent(downloadingPath) {
+		progress.DownloadingPath = downloadingPath
+	}
+	if tempPath := filepath.Join(g.LibraryPath, "steamapps", "temp", appDir); dirHasContent(tempPath) {
+		progress.TempPath = tempPath
+	}
+	return progress
+}
+
+// How much Steam has put in the downloading directory so far. Walks the whole directory,
+// so only call it when the number is about to be shown.
+func (p *DownloadProgress) DownloadingSize() int64 {
+	return dirContentSize(p.DownloadingPath)
+}
+
+// Same as DownloadingSize for the temp directory
+func (p *DownloadProgress) TempSize() int64 {
+	return dirContentSize(p.TempPath)
+}
+
+// Steam sometimes leaves the directories behind empty, those don't count
+func dirHasContent(dirPath string) bool {
+	entries, err := os.ReadDir(dirPath)
+	return err == nil && len(entries) > 0
+}
+
+func dirContentSize(dirPath string) int64 {
+	if dirPath == "" {
+		return 0
+	}
+	var size int64
+	filepath.WalkDir(dirPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// Steam moves files out of here while we look
+			return nil
+		}
+		if info, err := entry.Info(); err == nil && !entry.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
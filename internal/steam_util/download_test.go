@@ -0,0 +1,83 @@
+package steam_util
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDownloadProgress(t *testing.T) {
+	libraryPath := t.TempDir()
+	downloadingPath := filepath.Join(libraryPath, "steamapps", "downloading", "4000")
+	tempPath := filepath.Join(libraryPath, "steamapps", "temp", "4000")
+	// Steam leaves empty directories behind after an update
+	for _, dir := range []string{downloadingPath, tempPath} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	install := &GameInstall{AppId: 4000, LibraryPath: libraryPath, Manifest: &VdfAppManifest{}}
+	if download := install.DownloadProgress(); download.InProgress() {
+		t.Errorf("DownloadProgress() with empty directories = %+v, want nothing in progress", download)
+	}
+
+	if err := os.MkdirAll(filepath.Join(downloadingPath, "garrysmod", "bin"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for path, size := range map[string]int{"garrysmod/bin/client.so": 300, "hl2_linux": 200} {
+		if err := os.WriteFile(filepath.Join(downloadingPath, path), make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	install.Manifest.AppState.BytesToDownload = 1000
+	install.Manifest.AppState.BytesDownloaded = 250
+	download := install.DownloadProgress()
+	if !download.Active() || download.DownloadingPath != downloadingPath || download.TempPath != "" {
+		t.Errorf("DownloadProgress() = %+v, want only the downloading directory in use", download)
+	}
+	if download.DownloadFraction() != 0.25 || download.StageFraction() != -1 {
+		t.Errorf("fractions = %v, %v, want 0.25, -1", download.DownloadFraction(), download.StageFraction())
+	}
+	if size := download.DownloadingSize(); size != 500 {
+		t.Errorf("DownloadingSize() = %v, want 500", size)
+	}
+	if size := download.TempSize(); size != 0 {
+		t.Errorf("TempSize() = %v, want 0", size)
+	}
+}
+
+// A cancelled update leaves its files behind, that mustn't keep a fully installed game from being patched
+func TestDownloadProgressStaleDirectory(t *testing.T) {
+	libraryPath := t.TempDir()
+	writeTestFile(t, filepath.Join(libraryPath, "steamapps", "downloading", "4000", "hl2_linux"), 200)
+	install := &GameInstall{AppId: 4000, LibraryPath: libraryPath, Manifest: &VdfAppManifest{}}
+	install.Manifest.AppState.StateFlags = AppStateFullyInstalled
+
+	download := install.DownloadProgress()
+	if download.Active() || download.InProgress() {
+		t.Errorf("DownloadProgress() = %+v, want the leftover directory ignored", download)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := install.WaitUntilReady(ctx, nil); err != nil {
+		t.Errorf("WaitUntilReady() = %v, want the game ready straight away", err)
+	}
+
+	// The same directory counts once Steam says it's downloading again
+	install.Manifest.AppState.StateFlags = AppStateFullyInstalled | AppStateUpdateRunning | AppStateDownloading
+	if download := install.DownloadProgress(); !download.Active() {
+		t.Errorf("DownloadProgress() = %+v, want the update active", download)
+	}
+}
+
+func writeTestFile(t *testing.T, path string, size int) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,121 @@
+package steam_util
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+type LaunchCommand struct {
+	Path string
+	Args []string
+	Dir  string
+	// KEY=value pairs added on top of our own environment
+	Env []string
+}
+
+// Anything that can start a LaunchCommand, so tests can record what would have been run instead of starting Steam
+type Launcher interface {
+	Launch(command LaunchCommand) error
+}
+
+type ExecLauncher struct{}
+
+func (ExecLauncher) Launch(command LaunchCommand) error {
+	cmd := exec.Command(command.Path, command.Args...)
+	cmd.Dir = command.Dir
+	cmd.Env = append(os.Environ(), command.Env...)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("Couldn't start %s: %w", command.Path, err)
+	}
+	// Don't wait for the game to exit, just make sure it gets reaped when it does
+	go cmd.Wait()
+	return nil
+}
+
+// Going through Steam means it sets up the runtime/Proton and applies the user's launch options itself
+func LaunchGameViaSteam(launcher Launcher, steamPath string, appId uint32) error {
+	return launcher.Launch(steamLaunchCommand(steamPath, appId))
+}
+
+// Steam's verify integrity puts back the original files, undoing our patches
+func ValidateGameViaSteam(launcher Launcher, steamPath string, appId uint32) error {
+	return launcher.Launch(steamOpenURLCommand(steamPath, fmt.Sprintf("steam://validate/%v", appId)))
+}
+
+// For setups where Steam isn't managing the game, so we have to apply the launch options ourselves
+func LaunchGameDirectly(launcher Launcher, executable string, launchArguments string, launchOptions string) error {
+	return launcher.Launch(DirectLaunchCommand(executable, launchArguments, launchOptions))
+}
+
+// Build the command Steam would run for these launch options.
+// launchArguments are the ones from the appinfo launch entry, they always come right after the executable.
+// Anything before %command% is treated as environment variables and then a wrapper program,
+// without %command% all of the options are just arguments to the game.
+func DirectLaunchCommand(executable string, launchArguments string, launchOptions string) LaunchCommand {
+	command := LaunchCommand{
+		Path: executable,
+		Dir:  filepath.Dir(executable),
+	}
+	gameArgs := splitLaunchOptions(launchArguments)
+	options := splitLaunchOptions(launchOptions)
+	commandIndex := -1
+	for i, option := range options {
+		if option == "%command%" {
+			commandIndex = i
+			break
+		}
+	}
+	if commandIndex == -1 {
+		command.Args = append(gameArgs, options...)
+		return command
+	}
+
+	prefix := options[:commandIndex]
+	for len(prefix) > 0 && strings.Contains(prefix[0], "=") && !strings.HasPrefix(prefix[0], "=") {
+		command.Env = append(command.Env, prefix[0])
+		prefix = prefix[1:]
+	}
+	if len(prefix) > 0 {
+		command.Path = prefix[0]
+		command.Args = append(command.Args, prefix[1:]...)
+		command.Args = append(command.Args, executable)
+	}
+	command.Args = append(command.Args, gameArgs...)
+	command.Args = append(command.Args, options[commandIndex+1:]...)
+	return command
+}
+
+// Split on whitespace, keeping quoted sections together like a shell would
+func splitLaunchOptions(launchOptions string) []string {
+	var options []string
+	var current strings.Builder
+	inOption := false
+	var quote rune
+	for _, char := range launchOptions {
+		switch {
+		case quote != 0 && char == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(char)
+		case char == '"' || char == '\'':
+			quote = char
+			inOption = true
+		case char == ' ' || char == '\t' || char == '\n':
+			if inOption {
+				options = append(options, current.String())
+				current.Reset()
+				inOption = false
+			}
+		default:
+			current.WriteRune(char)
+			inOption = true
+		}
+	}
+	if inOption {
+		options = append(options, current.String())
+	}
+	return options
+}
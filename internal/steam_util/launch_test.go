@@ -0,0 +1,171 @@
+package steam_util
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+// Records commands instead of starting anything
+type recordingLauncher struct {
+	commands []LaunchCommand
+	err      error
+}
+
+func (l *recordingLauncher) Launch(command LaunchCommand) error {
+	l.commands = append(l.commands, command)
+	return l.err
+}
+
+func (l *recordingLauncher) assertLaunched(t *testing.T, want LaunchCommand) {
+	t.Helper()
+	if len(l.commands) != 1 {
+		t.Fatalf("launched %v commands, want 1: %+v", len(l.commands), l.commands)
+	}
+	if !reflect.DeepEqual(l.commands[0], want) {
+		t.Errorf("launched %+v, want %+v", l.commands[0], want)
+	}
+}
+
+func TestSplitLaunchOptions(t *testing.T) {
+	tests := []struct {
+		launchOptions string
+		want          []string
+	}{
+		{"", nil},
+		{" \t\n", nil},
+		{"-console", []string{"-console"}},
+		{"  -console   +map  gm_construct ", []string{"-console", "+map", "gm_construct"}},
+		{`-name "Garry's Mod" '+exec a b'`, []string{"-name", "Garry's Mod", "+exec a b"}},
+		{`MANGOHUD_CONFIG="fps, gpu" %command%`, []string{"MANGOHUD_CONFIG=fps, gpu", "%command%"}},
+		{`-a "" -b`, []string{"-a", "", "-b"}},
+		// An unterminated quote runs to the end
+		{`-a "b c`, []string{"-a", "b c"}},
+	}
+	for _, test := range tests {
+		if got := splitLaunchOptions(test.launchOptions); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("splitLaunchOptions(%q) = %q, want %q", test.launchOptions, got, test.want)
+		}
+	}
+}
+
+func TestDirectLaunchCommand(t *testing.T) {
+	gameDir := filepath.Join(t.TempDir(), "GarrysMod")
+	executable := filepath.Join(gameDir, "hl2.sh")
+	tests := []struct {
+		launchArguments string
+		launchOptions   string
+		want            LaunchCommand
+	}{
+		{"", "", LaunchCommand{Path: executable, Dir: gameDir}},
+		{"", "-console +map gm_construct", LaunchCommand{Path: executable, Args: []string{"-console", "+map", "gm_construct"}, Dir: gameDir}},
+		{"", "%command%", LaunchCommand{Path: executable, Dir: gameDir}},
+		{"", "%command% -console", LaunchCommand{Path: executable, Args: []string{"-console"}, Dir: gameDir}},
+		{"", "VAR=x %command%", LaunchCommand{Path: executable, Dir: gameDir, Env: []string{"VAR=x"}}},
+		{
+			"", "VAR=x OTHER=y gamemoderun %command% -novid",
+			LaunchCommand{Path: "gamemoderun", Args: []string{executable, "-novid"}, Dir: gameDir, Env: []string{"VAR=x", "OTHER=y"}},
+		},
+		{
+			"", `MANGOHUD_CONFIG="fps, gpu" mangohud --dlsym %command% -name "Garry's Mod"`,
+			LaunchCommand{
+				Path: "mangohud",
+				Args: []string{"--dlsym", executable, "-name", "Garry's Mod"},
+				Dir:  gameDir,
+				Env:  []string{"MANGOHUD_CONFIG=fps, gpu"},
+			},
+		},
+		// Only the variables before the wrapper are environment, the wrapper gets the rest as arguments
+		{
+			"", "env VAR=x %command%",
+			LaunchCommand{Path: "env", Args: []string{"VAR=x", executable}, Dir: gameDir},
+		},
+		// The launch entry's own arguments are part of %command%, so they always come before the user's
+		{"-game garrysmod", "", LaunchCommand{Path: executable, Args: []string{"-game", "garrysmod"}, Dir: gameDir}},
+		{"-game garrysmod", "-console", LaunchCommand{Path: executable, Args: []string{"-game", "garrysmod", "-console"}, Dir: gameDir}},
+		{"-game garrysmod", "%command% -console", LaunchCommand{Path: executable, Args: []string{"-game", "garrysmod", "-console"}, Dir: gameDir}},
+		{
+			`-game "garrys mod"`, "VAR=x gamemoderun %command% -novid",
+			LaunchCommand{Path: "gamemoderun", Args: []string{executable, "-game", "garrys mod", "-novid"}, Dir: gameDir, Env: []string{"VAR=x"}},
+		},
+	}
+	for _, test := range tests {
+		if got := DirectLaunchCommand(executable, test.launchArguments, test.launchOptions); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("DirectLaunchCommand(%q, %q) = %+v, want %+v", test.launchArguments, test.launchOptions, got, test.want)
+		}
+	}
+}
+
+func TestLaunchGameDirectly(t *testing.T) {
+	gameDir := filepath.Join(t.TempDir(), "GarrysMod")
+	executable := filepath.Join(gameDir, "hl2.sh")
+	launcher := &recordingLauncher{}
+	if err := LaunchGameDirectly(launcher, executable, "-steam", "VAR=x %command% -console"); err != nil {
+		t.Fatal(err)
+	}
+	launcher.assertLaunched(t, LaunchCommand{Path: executable, Args: []string{"-steam", "-console"}, Dir: gameDir, Env: []string{"VAR=x"}})
+
+	failing := &recordingLauncher{err: errors.New("no such file")}
+	if err := LaunchGameDirectly(failing, executable, "", ""); err != failing.err {
+		t.Errorf("LaunchGameDirectly() = %v, want the launcher's error", err)
+	}
+}
+
+func TestLaunchGameViaSteam(t *testing.T) {
+	steamPath := t.TempDir()
+	var want, wantValidate LaunchCommand
+	switch runtime.GOOS {
+	case "linux":
+		// The steam command wins over xdg-open when it's on the PATH
+		binDir := t.TempDir()
+		steamCommand := filepath.Join(binDir, "steam")
+		if err := os.WriteFile(steamCommand, []byte("#!/bin/sh\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("PATH", binDir)
+		want = LaunchCommand{Path: steamCommand, Args: []string{"-applaunch", "4000"}}
+		wantValidate = LaunchCommand{Path: steamCommand, Args: []string{"steam://validate/4000"}}
+	case "windows":
+		steamCommand := filepath.Join(steamPath, "steam.exe")
+		want = LaunchCommand{Path: steamCommand, Args: []string{"-applaunch", "4000"}}
+		wantValidate = LaunchCommand{Path: steamCommand, Args: []string{"steam://validate/4000"}}
+	case "darwin":
+		want = LaunchCommand{Path: "open", Args: []string{"steam://rungameid/4000"}}
+		wantValidate = LaunchCommand{Path: "open", Args: []string{"steam://validate/4000"}}
+	default:
+		t.Skipf("no Steam launch command for %v", runtime.GOOS)
+	}
+
+	launcher := &recordingLauncher{}
+	if err := LaunchGameViaSteam(launcher, steamPath, 4000); err != nil {
+		t.Fatal(err)
+	}
+	launcher.assertLaunched(t, want)
+
+	launcher = &recordingLauncher{}
+	if err := ValidateGameViaSteam(launcher, steamPath, 4000); err != nil {
+		t.Fatal(err)
+	}
+	launcher.assertLaunched(t, wantValidate)
+}
+
+func TestLaunchGameViaSteamWithoutSteamCommand(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("only Linux looks for the steam command")
+	}
+	t.Setenv("PATH", t.TempDir())
+	launcher := &recordingLauncher{}
+	if err := LaunchGameViaSteam(launcher, t.TempDir(), 4000); err != nil {
+		t.Fatal(err)
+	}
+	launcher.assertLaunched(t, LaunchCommand{Path: "xdg-open", Args: []string{"steam://rungameid/4000"}})
+
+	launcher = &recordingLauncher{}
+	if err := ValidateGameViaSteam(launcher, t.TempDir(), 4000); err != nil {
+		t.Fatal(err)
+	}
+	launcher.assertLaunched(t, LaunchCommand{Path: "xdg-open", Args: []string{"steam://validate/4000"}})
+}
@@ -0,0 +1,91 @@
+package steam_util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// What Libraryfolder.Status found
+const (
+	LIBRARY_STATUS_OK        = "ok"
+	LIBRARY_STATUS_MISSING   = "missing"
+	LIBRARY_STATUS_UNMOUNTED = "unmounted"
+)
+
+// Returned by FindGameInstalls when the only libraries that have the app can't be read right now
+type LibraryUnavailableError struct {
+	Path        string
+	Status      string
+	Explanation string
+}
+
+func (e *LibraryUnavailableError) Error() string {
+	return fmt.Sprintf("Steam library %v is %v: %v", e.Path, e.Status, e.Explanation)
+}
+
+// Status tells a library on a drive that isn't there right now apart from one that was deleted.
+// The explanation is empty when the library is fine.
+func (l Libraryfolder) Status() (string, string) {
+	if _, err := os.Stat(l.Path); err != nil {
+		if onMissingDrive(l.Path) {
+			return LIBRARY_STATUS_UNMOUNTED, "its drive isn't connected or mounted, connect it and try again"
+		}
+		return LIBRARY_STATUS_MISSING, "the folder doesn't exist anymore, remove it in Steam's storage settings"
+	}
+	if _, err := os.Stat(filepath.Join(l.Path, "steamapps")); err != nil {
+		// An empty directory is usually the mount point of a drive that isn't mounted
+		if entries, err := os.ReadDir(l.Path); err == nil && len(entries) == 0 {
+			return LIBRARY_STATUS_UNMOUNTED, "the folder is empty, its drive is probably not mounted"
+		}
+		return LIBRARY_STATUS_MISSING, "the folder has no steamapps directory, remove it in Steam's storage settings"
+	}
+	return LIBRARY_STATUS_OK, ""
+}
+
+// Removable and network drives take the directories below their mount point with them
+func onMissingDrive(path string) bool {
+	dir := filepath.Dir(path)
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		parentDir := filepath.Dir(dir)
+		if parentDir == dir {
+			// Not even the root exists, which is a Windows drive letter that's gone
+			return true
+		}
+		dir = parentDir
+	}
+	for _, mountDir := range []string{"/media", "/run/media", "/mnt", "/Volumes"} {
+		if dir == mountDir || filepath.Dir(dir) == mountDir {
+			return true
+		}
+	}
+	return false
+}
+
+// LibrariesWithApp uses the apps list Steam keeps in libraryfolders.vdf to find the libraries an app is installed in
+func (l *VdfLibraryFolders) LibrariesWithApp(appId uint32) []string {
+	var keys []string
+	for _, key := range sortedNumericKeys(l.Libraryfolders) {
+		if _, found := l.Libraryfolders[key].Apps[appId]; found {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Libraries that claim the app first, then the rest in case libraryfolders.vdf is out of date
+// or from a Steam version that didn't list apps
+func (l *VdfLibraryFolders) librariesToSearch(appId uint32) ([]string, int) {
+	claimingKeys := l.LibrariesWithApp(appId)
+	keys := slices.Clone(claimingKeys)
+	for _, key := range sortedNumericKeys(l.Libraryfolders) {
+		if !slices.Contains(claimingKeys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, len(claimingKeys)
+}
@@ -0,0 +1,93 @@
+package steam_util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLibraryfolderStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		// Creates whatever the library needs inside dir and returns its path
+		setup           func(t *testing.T, dir string) string
+		want            string
+		wantExplanation bool
+	}{
+		{
+			"ok",
+			func(t *testing.T, dir string) string {
+				mkdirTest(t, filepath.Join(dir, "SteamLibrary", "steamapps"))
+				return filepath.Join(dir, "SteamLibrary")
+			},
+			LIBRARY_STATUS_OK, false,
+		},
+		{
+			"deleted",
+			func(t *testing.T, dir string) string {
+				return filepath.Join(dir, "SteamLibrary")
+			},
+			LIBRARY_STATUS_MISSING, true,
+		},
+		{
+			"deleted with its parent",
+			func(t *testing.T, dir string) string {
+				return filepath.Join(dir, "Games", "SteamLibrary")
+			},
+			LIBRARY_STATUS_MISSING, true,
+		},
+		{
+			"empty mount point",
+			func(t *testing.T, dir string) string {
+				mkdirTest(t, filepath.Join(dir, "SteamLibrary"))
+				return filepath.Join(dir, "SteamLibrary")
+			},
+			LIBRARY_STATUS_UNMOUNTED, true,
+		},
+		{
+			"other files but no steamapps",
+			func(t *testing.T, dir string) string {
+				writeTestFile(t, filepath.Join(dir, "SteamLibrary", "libraryfolder.vdf"), 10)
+				return filepath.Join(dir, "SteamLibrary")
+			},
+			LIBRARY_STATUS_MISSING, true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			library := Libraryfolder{Path: test.setup(t, t.TempDir())}
+			status, explanation := library.Status()
+			if status != test.want {
+				t.Errorf("Status() = %v (%v), want %v", status, explanation, test.want)
+			}
+			if (explanation != "") != test.wantExplanation {
+				t.Errorf("Status() explanation = %q, want one: %v", explanation, test.wantExplanation)
+			}
+		})
+	}
+}
+
+// A library on a drive that isn't connected is missing along with the drive's directory under /media or /mnt
+func TestLibraryfolderStatusMissingDrive(t *testing.T) {
+	var mountDir string
+	for _, dir := range []string{"/media", "/run/media", "/mnt", "/Volumes"} {
+		if stat, err := os.Stat(dir); err == nil && stat.IsDir() {
+			mountDir = dir
+			break
+		}
+	}
+	if mountDir == "" {
+		t.Skip("no mount directory on this system")
+	}
+	library := Libraryfolder{Path: filepath.Join(mountDir, "gmodcefcodecfix-test-drive", "SteamLibrary")}
+	if status, _ := library.Status(); status != LIBRARY_STATUS_UNMOUNTED {
+		t.Errorf("Status() = %v, want %v", status, LIBRARY_STATUS_UNMOUNTED)
+	}
+}
+
+func mkdirTest(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
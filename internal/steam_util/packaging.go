@@ -0,0 +1,140 @@
+package steam_util
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// How Steam was installed, sandboxed installs can't see the whole file system
+const (
+	PACKAGING_NATIVE  = "native"
+	PACKAGING_FLATPAK = "flatpak"
+	PACKAGING_SNAP    = "snap"
+)
+
+const FLATPAK_STEAM_APP_ID = "com.valvesoftware.Steam"
+
+// A directory the sandbox was given access to
+type SandboxFilesystem struct {
+	Path     string
+	ReadOnly bool
+}
+
+type SteamPackaging struct {
+	Type    string
+	HomeDir string
+	// Host directory the sandbox keeps its own files in, empty for native installs
+	SandboxHome string
+	// Directories outside SandboxHome the Flatpak can see, from its permissions and overrides
+	Filesystems []SandboxFilesystem
+	// Whether the Snap may use /media, /run/media and /mnt
+	SnapRemovableMedia bool
+}
+
+func (p *SteamPackaging) IsSandboxed() bool {
+	return p != nil && p.Type != PACKAGING_NATIVE
+}
+
+// HostPath translates a path the sandboxed client wrote to libraryfolders.vdf into one we can open.
+// Flatpak mounts its persisted home over the real one, so anything in the home directory the app
+// wasn't given access to really lives in SandboxHome. Snap doesn't move anything.
+func (p *SteamPackaging) HostPath(sandboxPath string) string {
+	if p == nil || p.Type != PACKAGING_FLATPAK {
+		return sandboxPath
+	}
+	if _, granted := p.sandboxFilesystem(sandboxPath); granted || isWithin(p.SandboxHome, sandboxPath) {
+		return sandboxPath
+	}
+	if relPath, ok := relativeTo(p.HomeDir, sandboxPath); ok {
+		return filepath.Join(p.SandboxHome, relPath)
+	}
+	return sandboxPath
+}
+
+// LibraryAccessWarning explains why the sandboxed client can't use the library at hostPath,
+// or returns "" if it can
+func (p *SteamPackaging) LibraryAccessWarning(hostPath string) string {
+	if !p.IsSandboxed() || isWithin(p.SandboxHome, hostPath) {
+		return ""
+	}
+	switch p.Type {
+	case PACKAGING_FLATPAK:
+		filesystem, granted := p.sandboxFilesystem(hostPath)
+		if !granted {
+			return fmt.Sprintf("the Flatpak Steam can't see this library, allow it with: flatpak override --user --filesystem=%v %v",
+				hostPath, FLATPAK_STEAM_APP_ID)
+		}
+		if filesystem.ReadOnly {
+			return fmt.Sprintf("the Flatpak Steam can only read this library, allow writing with: flatpak override --user --filesystem=%v:rw %v",
+				hostPath, FLATPAK_STEAM_APP_ID)
+		}
+	case PACKAGING_SNAP:
+		if relPath, ok := relativeTo(p.HomeDir, hostPath); ok {
+			// The home interface leaves out hidden files and directories
+			if strings.HasPrefix(relPath, ".") {
+				return "the Snap Steam can't see hidden directories in your home directory, move the library somewhere else"
+			}
+			return ""
+		}
+		for _, mediaDir := range []string{"/media", "/run/media", "/mnt"} {
+			if !isWithin(mediaDir, hostPath) {
+				continue
+			}
+			if !p.SnapRemovableMedia {
+				return "the Snap Steam can't see removable drives, allow it with: snap connect steam:removable-media"
+			}
+			return ""
+		}
+		return "the Snap Steam can only see your home directory and removable drives"
+	}
+	return ""
+}
+
+// LibraryAccessWarning for every library that has one, by host path
+func (p *SteamPackaging) CheckLibraries(steamLibraries *VdfLibraryFolders) map[string]string {
+	warnings := make(map[string]string)
+	for _, steamLib := range steamLibraries.Libraryfolders {
+		// Unavailable libraries get their own warning
+		if status, _ := steamLib.Status(); status != LIBRARY_STATUS_OK {
+			continue
+		}
+		if warning := p.LibraryAccessWarning(steamLib.Path); warning != "" {
+			warnings[steamLib.Path] = warning
+		}
+	}
+	return warnings
+}
+
+// The most specific permission that covers path
+func (p *SteamPackaging) sandboxFilesystem(path string) (SandboxFilesystem, bool) {
+	var best SandboxFilesystem
+	found := false
+	for _, filesystem := range p.Filesystems {
+		if isWithin(filesystem.Path, path) && (!found || len(filesystem.Path) > len(best.Path)) {
+			best = filesystem
+			found = true
+		}
+	}
+	return best, found
+}
+
+// Whether path is dir or anything below it
+func isWithin(dir string, path string) bool {
+	_, ok := relativeTo(dir, path)
+	return ok
+}
+
+func relativeTo(dir string, path string) (string, bool) {
+	if dir == "" {
+		return "", false
+	}
+	relPath, err := filepath.Rel(dir, path)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	if relPath == "." {
+		relPath = ""
+	}
+	return relPath, true
+}
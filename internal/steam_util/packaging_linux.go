@@ -0,0 +1,191 @@
+package steam_util
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Where system wide Flatpak installs keep apps and overrides, a variable so tests can use fixtures
+var flatpakSystemDir = "/var/lib/flatpak"
+
+// Tells Flatpak and Snap installs apart by where steamPath really is
+func GetSteamPackaging(steamPath string) (*SteamPackaging, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("error getting home directory: %w", err)
+	}
+	// ~/.steam/steam is a symlink into wherever Steam actually lives
+	if resolvedPath, err := filepath.EvalSymlinks(steamPath); err == nil {
+		steamPath = resolvedPath
+	}
+
+	packaging := &SteamPackaging{Type: PACKAGING_NATIVE, HomeDir: homeDir}
+	flatpakHome := filepath.Join(homeDir, ".var", "app", FLATPAK_STEAM_APP_ID)
+	snapHome := filepath.Join(homeDir, "snap", "steam")
+	if isWithin(flatpakHome, steamPath) {
+		packaging.Type = PACKAGING_FLATPAK
+		packaging.SandboxHome = flatpakHome
+		packaging.Filesystems = flatpakFilesystems(homeDir)
+	} else if isWithin(snapHome, steamPath) {
+		packaging.Type = PACKAGING_SNAP
+		packaging.SandboxHome = snapHome
+		packaging.SnapRemovableMedia = snapInterfaceConnected("steam", "removable-media")
+	}
+	return packaging, nil
+}
+
+// Combine the app's own permissions with the overrides the same way flatpak does:
+// metadata, then global overrides, then the app's overrides, system installation before user installation.
+// A "!" in front of an entry takes it away again.
+func flatpakFilesystems(homeDir string) []SandboxFilesystem {
+	userFlatpakDir := filepath.Join(homeDir, ".local", "share", "flatpak")
+	if dataHome := os.Getenv("XDG_DATA_HOME"); dataHome != "" {
+		userFlatpakDir = filepath.Join(dataHome, "flatpak")
+	}
+	keyFiles := []string{
+		filepath.Join(flatpakSystemDir, "app", FLATPAK_STEAM_APP_ID, "current", "active", "metadata"),
+		filepath.Join(userFlatpakDir, "app", FLATPAK_STEAM_APP_ID, "current", "active", "metadata"),
+		filepath.Join(flatpakSystemDir, "overrides", "global"),
+		filepath.Join(flatpakSystemDir, "overrides", FLATPAK_STEAM_APP_ID),
+		filepath.Join(userFlatpakDir, "overrides", "global"),
+		filepath.Join(userFlatpakDir, "overrides", FLATPAK_STEAM_APP_ID),
+	}
+
+	readOnlyByPath := make(map[string]bool)
+	var paths []string
+	for _, keyFile := range keyFiles {
+		entries, err := readFlatpakFilesystems(keyFile)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				slog.Warn("Couldn't read Flatpak permissions, skipping", "path", keyFile, "err", err)
+			}
+			continue
+		}
+		for _, entry := range entries {
+			negated := strings.HasPrefix(entry, "!")
+			path, readOnly := flatpakFilesystemPath(homeDir, strings.TrimPrefix(entry, "!"))
+			if path == "" {
+				continue
+			}
+			if negated {
+				// Taken out of the order too, so granting it again later doesn't list it twice
+				delete(readOnlyByPath, path)
+				paths = slices.DeleteFunc(paths, func(other string) bool { return other == path })
+				continue
+			}
+			if _, found := readOnlyByPath[path]; !found {
+				paths = append(paths, path)
+			}
+			readOnlyByPath[path] = readOnly
+		}
+	}
+
+	var filesystems []SandboxFilesystem
+	for _, path := range paths {
+		filesystems = append(filesystems, SandboxFilesystem{Path: path, ReadOnly: readOnlyByPath[path]})
+	}
+	return filesystems
+}
+
+// The filesystems key from the [Context] group of a metadata or overrides file
+func readFlatpakFilesystems(keyFilePath string) ([]string, error) {
+	keyFile, err := os.Open(keyFilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer keyFile.Close()
+
+	var entries []string
+	inContext := false
+	scanner := bufio.NewScanner(keyFile)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inContext = line == "[Context]"
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !inContext || !found || strings.TrimSpace(key) != "filesystems" {
+			continue
+		}
+		for _, entry := range strings.Split(value, ";") {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// Where a filesystems entry like "~/Games:ro" or "xdg-data/Steam" points to.
+// Entries that can't hold a Steam library, like host-etc, give "".
+func flatpakFilesystemPath(homeDir string, entry string) (string, bool) {
+	readOnly := false
+	if name, mode, found := strings.Cut(entry, ":"); found {
+		entry = name
+		readOnly = mode == "ro"
+	}
+
+	name, subPath, _ := strings.Cut(entry, "/")
+	var base string
+	switch name {
+	case "host", "host-all":
+		base = "/"
+	case "home", "~":
+		base = homeDir
+	case "":
+		return filepath.Clean(entry), readOnly
+	case "xdg-data":
+		base = xdgDir("XDG_DATA_HOME", homeDir, ".local/share")
+	case "xdg-config":
+		base = xdgDir("XDG_CONFIG_HOME", homeDir, ".config")
+	case "xdg-cache":
+		base = xdgDir("XDG_CACHE_HOME", homeDir, ".cache")
+	// Assumes the default English names, user-dirs.dirs could move these
+	case "xdg-desktop":
+		base = filepath.Join(homeDir, "Desktop")
+	case "xdg-documents":
+		base = filepath.Join(homeDir, "Documents")
+	case "xdg-download":
+		base = filepath.Join(homeDir, "Downloads")
+	case "xdg-music":
+		base = filepath.Join(homeDir, "Music")
+	case "xdg-pictures":
+		base = filepath.Join(homeDir, "Pictures")
+	case "xdg-videos":
+		base = filepath.Join(homeDir, "Videos")
+	default:
+		return "", false
+	}
+	return filepath.Join(base, subPath), readOnly
+}
+
+func xdgDir(envName string, homeDir string, defaultDir string) string {
+	if dir := os.Getenv(envName); dir != "" {
+		return dir
+	}
+	return filepath.Join(homeDir, defaultDir)
+}
+
+// Parses `snap connections`, which lists "interface plug slot notes" and "-" as the slot of unconnected plugs
+func snapInterfaceConnected(snapName string, interfaceName string) bool {
+	output, err := exec.Command("snap", "connections", snapName).Output()
+	if err != nil {
+		return false
+	}
+	plug := snapName + ":" + interfaceName
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[1] == plug && fields[2] != "-" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+package steam_util
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testHomeDir = "/home/gabe"
+
+var testFlatpakHome = filepath.Join(testHomeDir, ".var", "app", FLATPAK_STEAM_APP_ID)
+
+// The Flatpak permissions from testdata/flatpak, XDG_DATA_HOME points at the user installation in there
+func fixtureFlatpakPackaging(t *testing.T) (*SteamPackaging, string) {
+	t.Helper()
+	fixtureDir, err := filepath.Abs(filepath.Join("testdata", "flatpak"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(dir string) { flatpakSystemDir = dir }(flatpakSystemDir)
+	flatpakSystemDir = filepath.Join(fixtureDir, "system")
+	dataHome := filepath.Join(fixtureDir, "user")
+	t.Setenv("XDG_DATA_HOME", dataHome)
+	return &SteamPackaging{
+		Type:        PACKAGING_FLATPAK,
+		HomeDir:     testHomeDir,
+		SandboxHome: testFlatpakHome,
+		Filesystems: flatpakFilesystems(testHomeDir),
+	}, dataHome
+}
+
+func TestReadFlatpakFilesystems(t *testing.T) {
+	tests := []struct {
+		keyFile string
+		want    []string
+	}{
+		{
+			"system/app/com.valvesoftware.Steam/current/active/metadata",
+			[]string{"xdg-music:ro", "xdg-pictures:ro", "xdg-run/app/com.discordapp.Discord:create"},
+		},
+		{"system/overrides/global", []string{"/mnt/games:ro"}},
+		{"system/overrides/com.valvesoftware.Steam", []string{"/mnt/games", "!xdg-pictures"}},
+		// Only the [Context] group counts, and spaces around keys and entries don't matter
+		{
+			"user/flatpak/overrides/com.valvesoftware.Steam",
+			[]string{"~/Games", "!/mnt/games", "/mnt/games:ro", "home/Games/", "xdg-data/Steam", "host-etc"},
+		},
+	}
+	for _, test := range tests {
+		got, err := readFlatpakFilesystems(filepath.Join("testdata", "flatpak", filepath.FromSlash(test.keyFile)))
+		if err != nil {
+			t.Errorf("readFlatpakFilesystems(%v) failed: %v", test.keyFile, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("readFlatpakFilesystems(%v) = %q, want %q", test.keyFile, got, test.want)
+		}
+	}
+}
+
+func TestFlatpakFilesystems(t *testing.T) {
+	packaging, dataHome := fixtureFlatpakPackaging(t)
+	want := []SandboxFilesystem{
+		{Path: "/home/gabe/Music", ReadOnly: true},
+		// ~/Games and home/Games/ are the same directory
+		{Path: "/home/gabe/Games"},
+		// Taken away and then granted read only again, so it moves to the end and is listed once
+		{Path: "/mnt/games", ReadOnly: true},
+		{Path: filepath.Join(dataHome, "Steam")},
+	}
+	if !reflect.DeepEqual(packaging.Filesystems, want) {
+		t.Errorf("flatpakFilesystems() = %+v, want %+v", packaging.Filesystems, want)
+	}
+}
+
+func TestFlatpakFilesystemPath(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", "")
+	tests := []struct {
+		entry        string
+		wantPath     string
+		wantReadOnly bool
+	}{
+		{"/mnt/games", "/mnt/games", false},
+		{"/mnt/games/:ro", "/mnt/games", true},
+		{"/mnt/games:rw", "/mnt/games", false},
+		{"~/Games", "/home/gabe/Games", false},
+		{"home", "/home/gabe", false},
+		{"host:ro", "/", true},
+		{"xdg-data/Steam", "/home/gabe/.local/share/Steam", false},
+		{"xdg-download/Steam:ro", "/home/gabe/Downloads/Steam", true},
+		{"host-etc", "", false},
+		{"xdg-run/app/com.discordapp.Discord:create", "", false},
+	}
+	for _, test := range tests {
+		path, readOnly := flatpakFilesystemPath(testHomeDir, test.entry)
+		if path != test.wantPath || readOnly != test.wantReadOnly {
+			t.Errorf("flatpakFilesystemPath(%q) = %q, %v, want %q, %v", test.entry, path, readOnly, test.wantPath, test.wantReadOnly)
+		}
+	}
+}
+
+func TestHostPath(t *testing.T) {
+	flatpak, dataHome := fixtureFlatpakPackaging(t)
+	snap := &SteamPackaging{Type: PACKAGING_SNAP, HomeDir: testHomeDir, SandboxHome: "/home/gabe/snap/steam"}
+	native := &SteamPackaging{Type: PACKAGING_NATIVE, HomeDir: testHomeDir}
+	tests := []struct {
+		name      string
+		packaging *SteamPackaging
+		path      string
+		want      string
+	}{
+		{"granted home directory", flatpak, "/home/gabe/Games/SteamLibrary", "/home/gabe/Games/SteamLibrary"},
+		{"granted outside home", flatpak, "/mnt/games/SteamLibrary", "/mnt/games/SteamLibrary"},
+		{"granted xdg-data", flatpak, filepath.Join(dataHome, "Steam"), filepath.Join(dataHome, "Steam")},
+		{"already in the sandbox", flatpak, testFlatpakHome + "/data/Steam", testFlatpakHome + "/data/Steam"},
+		{"home not granted", flatpak, "/home/gabe/.local/share/Steam", testFlatpakHome + "/.local/share/Steam"},
+		{"grant taken away", flatpak, "/home/gabe/Pictures/SteamLibrary", testFlatpakHome + "/Pictures/SteamLibrary"},
+		{"outside home not granted", flatpak, "/media/drive/SteamLibrary", "/media/drive/SteamLibrary"},
+		{"snap", snap, "/home/gabe/.local/share/Steam", "/home/gabe/.local/share/Steam"},
+		{"native", native, "/home/gabe/.local/share/Steam", "/home/gabe/.local/share/Steam"},
+		{"no packaging", nil, "/home/gabe/.local/share/Steam", "/home/gabe/.local/share/Steam"},
+	}
+	for _, test := range tests {
+		if got := test.packaging.HostPath(test.path); got != test.want {
+			t.Errorf("%v: HostPath(%v) = %v, want %v", test.name, test.path, got, test.want)
+		}
+	}
+}
+
+func TestLibraryAccessWarning(t *testing.T) {
+	flatpak, _ := fixtureFlatpakPackaging(t)
+	snap := &SteamPackaging{Type: PACKAGING_SNAP, HomeDir: testHomeDir, SandboxHome: "/home/gabe/snap/steam"}
+	snapWithMedia := &SteamPackaging{Type: PACKAGING_SNAP, HomeDir: testHomeDir, SandboxHome: "/home/gabe/snap/steam", SnapRemovableMedia: true}
+	native := &SteamPackaging{Type: PACKAGING_NATIVE, HomeDir: testHomeDir}
+	// wantWarning is a part of the warning, "" means no warning
+	tests := []struct {
+		name        string
+		packaging   *SteamPackaging
+		path        string
+		wantWarning string
+	}{
+		{"flatpak granted", flatpak, "/home/gabe/Games/SteamLibrary", ""},
+		{"flatpak sandbox home", flatpak, testFlatpakHome + "/data/Steam", ""},
+		{"flatpak read only", flatpak, "/mnt/games/SteamLibrary", "--filesystem=/mnt/games/SteamLibrary:rw"},
+		{"flatpak read only home directory", flatpak, "/home/gabe/Music/SteamLibrary", "can only read"},
+		{"flatpak not granted", flatpak, "/media/drive/SteamLibrary", "--filesystem=/media/drive/SteamLibrary com.valvesoftware.Steam"},
+		{"flatpak grant taken away", flatpak, "/home/gabe/Pictures/SteamLibrary", "can't see this library"},
+		{"snap home", snap, "/home/gabe/Games/SteamLibrary", ""},
+		{"snap sandbox home", snap, "/home/gabe/snap/steam/common/.local/share/Steam", ""},
+		{"snap hidden directory", snap, "/home/gabe/.steamlibrary", "hidden directories"},
+		{"snap removable media", snap, "/media/drive/SteamLibrary", "snap connect steam:removable-media"},
+		{"snap removable media connected", snapWithMedia, "/run/media/drive/SteamLibrary", ""},
+		{"snap elsewhere", snapWithMedia, "/opt/SteamLibrary", "only see your home directory"},
+		{"native", native, "/opt/SteamLibrary", ""},
+		{"no packaging", nil, "/opt/SteamLibrary", ""},
+	}
+	for _, test := range tests {
+		got := test.packaging.LibraryAccessWarning(test.path)
+		if test.wantWarning == "" && got != "" {
+			t.Errorf("%v: LibraryAccessWarning(%v) = %q, want no warning", test.name, test.path, got)
+		} else if !strings.Contains(got, test.wantWarning) {
+			t.Errorf("%v: LibraryAccessWarning(%v) = %q, want a warning containing %q", test.name, test.path, got, test.wantWarning)
+		}
+	}
+}
@@ -0,0 +1,6 @@
+package steam_util
+
+// Steam isn't sandboxed here
+func GetSteamPackaging(steamPath string) (*SteamPackaging, error) {
+	return &SteamPackaging{Type: PACKAGING_NATIVE}, nil
+}
@@ -0,0 +1,124 @@
+package steam_util
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// What ProtonPrefix.Health found
+const (
+	PREFIX_HEALTH_OK               = "ok"
+	PREFIX_HEALTH_NOT_CREATED      = "not created"
+	PREFIX_HEALTH_MISSING_PFX      = "missing pfx"
+	PREFIX_HEALTH_VERSION_MISMATCH = "version mismatch"
+)
+
+// Proton keeps one Wine prefix per app in steamapps/compatdata/<appid>.
+// Version and CreatedWith are empty if the files they come from are missing.
+type ProtonPrefix struct {
+	AppId          uint32
+	CompatDataPath string
+	// Proton's prefix format from the version file, e.g. "9.0-200"
+	Version string
+	// The Proton build that last set up the prefix, the first line of config_info
+	CreatedWith string
+	// The rest of config_info, where that build keeps its files
+	ConfigInfo []string
+}
+
+// The Wine prefix itself, drive_c and the registry live in here
+func (p *ProtonPrefix) PrefixPath() string {
+	return filepath.Join(p.CompatDataPath, "pfx")
+}
+
+// FindProtonPrefix looks for the app's compatdata directory. Steam creates it in the library the app is
+// installed to, but older prefixes or ones from a library that was moved can be in the main Steam library
+// or any other library instead. If there isn't one yet, CompatDataPath is where Steam will create it.
+// steamLibraries can be nil.
+func FindProtonPrefix(steamPath string, steamLibraries *VdfLibraryFolders, install *GameInstall) (*ProtonPrefix, error) {
+	libraryPaths := []string{install.LibraryPath, steamPath}
+	if steamLibraries != nil {
+		for _, key := range sortedNumericKeys(steamLibraries.Libraryfolders) {
+			libraryPaths = append(libraryPaths, steamLibraries.Libraryfolders[key].Path)
+		}
+	}
+
+	prefix := &ProtonPrefix{
+		AppId:          install.AppId,
+		CompatDataPath: compatDataPath(install.LibraryPath, install.AppId),
+	}
+	for _, libraryPath := range libraryPaths {
+		compatDataPath := compatDataPath(libraryPath, install.AppId)
+		if stat, err := os.Stat(compatDataPath); err == nil && stat.IsDir() {
+			prefix.CompatDataPath = compatDataPath
+			break
+		}
+	}
+
+	if version, err := os.ReadFile(filepath.Join(prefix.CompatDataPath, "version")); err == nil {
+		prefix.Version = strings.TrimSpace(string(version))
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	configInfo, err := os.Open(filepath.Join(prefix.CompatDataPath, "config_info"))
+	if errors.Is(err, os.ErrNotExist) {
+		return prefix, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer configInfo.Close()
+	scanner := bufio.NewScanner(configInfo)
+	if scanner.Scan() {
+		prefix.CreatedWith = lastField(scanner.Text())
+	}
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			prefix.ConfigInfo = append(prefix.ConfigInfo, line)
+		}
+	}
+	return prefix, scanner.Err()
+}
+
+func compatDataPath(libraryPath string, appId uint32) string {
+	return filepath.Join(libraryPath, "steamapps", "compatdata", fmt.Sprintf("%v", appId))
+}
+
+// Health compares the prefix with the tool Steam will run the app with, which can be nil.
+// The explanation is empty when everything is fine.
+func (p *ProtonPrefix) Health(tool *CompatTool) (string, string) {
+	if _, err := os.Stat(p.CompatDataPath); err != nil {
+		return PREFIX_HEALTH_NOT_CREATED, "Proton creates the prefix the first time the game is launched"
+	}
+	if stat, err := os.Stat(p.PrefixPath()); err != nil || !stat.IsDir() {
+		return PREFIX_HEALTH_MISSING_PFX, "the compatdata directory has no pfx in it, reset the prefix so Proton can create a new one"
+	}
+	if tool != nil && tool.Version != "" && p.CreatedWith != "" && tool.Version != p.CreatedWith {
+		return PREFIX_HEALTH_VERSION_MISMATCH, fmt.Sprintf(
+			"the prefix was set up by %v but the game runs with %v, Proton upgrades it on the next launch but can't downgrade it",
+			p.CreatedWith, tool.Version)
+	}
+	return PREFIX_HEALTH_OK, ""
+}
+
+// BackupAndReset moves the whole compatdata directory aside so Proton creates a fresh prefix on the next launch.
+// Saves and settings that only exist in the prefix are kept in the backup, which is returned.
+// The game must not be running.
+func (p *ProtonPrefix) BackupAndReset() (string, error) {
+	if _, err := os.Stat(p.CompatDataPath); err != nil {
+		return "", fmt.Errorf("there's no prefix to reset: %w", err)
+	}
+	backupPath := fmt.Sprintf("%v.backup-%v", p.CompatDataPath, time.Now().Format("20060102-150405"))
+	if err := os.Rename(p.CompatDataPath, backupPath); err != nil {
+		return "", fmt.Errorf("couldn't back up the prefix: %w", err)
+	}
+	p.Version = ""
+	p.CreatedWith = ""
+	p.ConfigInfo = nil
+	return backupPath, nil
+}
@@ -3,6 +3,7 @@ package steam_util
 import (
 	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"reflect"
 	"strconv"
@@ -21,7 +22,8 @@ type VdfLoginUsers struct {
 	Users map[uint64]SteamUser
 }
 type SteamUser struct {
-	SteamID64   uint64
+	// A string in JSON, JavaScript can't hold it as a number
+	SteamID64   uint64 `json:",string"`
 	AccountId   string
 	AccountName string
 	PersonaName string
@@ -33,19 +35,56 @@ type VdfLibraryFolders struct {
 	Libraryfolders map[string]Libraryfolder
 }
 type Libraryfolder struct {
-	Path string
+	Path      string
+	Label     string
+	ContentID uint64
+	// 0 for the library in the Steam directory
+	TotalSize uint64
+	// Size on disk by app id
+	Apps map[uint32]uint64
+	// Where a sandboxed Steam sees the library, empty unless it's different from Path
+	SandboxPath string
 }
 
 type VdfAppManifest struct {
 	AppState struct {
+		InstallDir          string
+		BuildID             uint32
+		TargetBuildID       uint32
+		LastUpdated         int64
+		SizeOnDisk          uint64
+		BytesToDownload     uint64
+		BytesDownloaded     uint64
+		BytesToStage        uint64
+		BytesStaged         uint64
 		ScheduledAutoUpdate int
-		StateFlags          int
-		UserConfig          struct {
-			Language string
-			BetaKey  string
-		}
+		StateFlags          AppStateFlags
+		InstalledDepots     map[uint32]InstalledDepot
+		UserConfig          AppManifestConfig
+		MountedConfig       AppManifestConfig
 	}
 }
+type InstalledDepot struct {
+	Manifest uint64
+	Size     uint64
+}
+
+// UserConfig is what the user asked for, MountedConfig is what is actually on disk
+type AppManifestConfig struct {
+	Language string
+	BetaKey  string
+}
+
+// Not read from a vdf file directly, this ties an appmanifest to the library it was found in.
+type GameInstall struct {
+	AppId        uint32
+	LibraryKey   string
+	LibraryPath  string
+	ManifestPath string
+	InstallDir   string
+	GamePath     string
+	Manifest     *VdfAppManifest
+}
 
 type VdfConfig struct {
 	InstallConfigStore struct {
@@ -59,7 +98,32 @@ type VdfConfig struct {
 	}
 }
 type CompatToolMapping struct {
-	Name string
+	Name     string
+	Config   string
+	Priority int
+}
+
+// compatibilitytool.vdf, which custom tools in compatibilitytools.d use to register themselves
+type VdfCompatibilityTool struct {
+	CompatibilityTools struct {
+		Compat_Tools map[string]CompatToolDefinition
+	}
+}
+type CompatToolDefinition struct {
+	Install_Path string
+	Display_Name string
+	From_OsList  string
+	To_OsList    string
+}
+
+// toolmanifest.vdf, which every compat tool has including the ones Steam ships
+type VdfToolManifest struct {
+	Manifest struct {
+		Version                  string
+		CommandLine              string
+		Require_Tool_AppId       uint32
+		Compatmanager_Layer_Name string
+	}
 }
 
 type VdfAppInfo struct {
@@ -68,6 +132,9 @@ type VdfAppInfo struct {
 			Config struct {
 				Launch map[string]Launch
 			}
+			Depots struct {
+				Branches map[string]AppBranch
+			}
 		}
 	}
 }
@@ -76,7 +143,17 @@ type Launch struct {
 		BetaKey string
 		OsList  string
 	}
-	Executable string
+	Executable  string
+	Arguments   string
+	Description string
+	Type        string
+}
+
+type AppBranch struct {
+	BuildID     uint32
+	TimeUpdated int64
+	Description string
+	PwdRequired int
 }
 
 type VdfLocalConfig struct {
@@ -84,7 +161,8 @@ type VdfLocalConfig struct {
 		Software struct {
 			Valve struct {
 				Steam struct {
-					Apps map[uint32]AppLocalConfig
+					Apps              map[uint32]AppLocalConfig
+					CompatToolMapping map[uint32]CompatToolMapping
 				}
 			}
 		}
@@ -136,7 +214,7 @@ func populateStructFromMap(dataUnknown interface{}, result interface{}) error {
 			continue
 		}
 		if !field.CanSet() {
-			fmt.Printf("Cannot set field: %s\n", structFieldName)
+			slog.Warn("Cannot set field", "field", structFieldName)
 			continue
 		}
 
@@ -182,7 +260,7 @@ func populateStructFromMap(dataUnknown interface{}, result interface{}) error {
 					// Convert string key to the appropriate integer type
 					uintKey, err := strconv.ParseUint(mapKey, 10, 64)
 					if err != nil {
-						fmt.Printf("Cannot convert string to integer for key %s in field %s: %s\n", mapKey, structFieldName, err)
+						slog.Warn("Cannot convert map key to integer", "field", structFieldName, "key", mapKey, "err", err)
 						continue
 					}
 
@@ -198,7 +276,12 @@ func populateStructFromMap(dataUnknown interface{}, result interface{}) error {
 						}
 						newMap.SetMapIndex(intKey, nestedStructPtr.Elem())
 					} else {
-						newMap.SetMapIndex(intKey, reflect.ValueOf(mapValue))
+						elemValue, err := convertScalar(mapValue, fieldType.Elem())
+						if err != nil {
+							slog.Warn("Cannot convert map value", "field", structFieldName, "key", mapKey, "err", err)
+							continue
+						}
+						newMap.SetMapIndex(intKey, elemValue)
 					}
 				}
 				field.Set(newMap)
@@ -219,7 +302,7 @@ func populateStructFromMap(dataUnknown interface{}, result interface{}) error {
 						}
 						newMap.SetMapIndex(reflect.ValueOf(mapKey), nestedStructPtr.Elem())
 					} else {
-						fmt.Printf("Cannot convert value to map[string]%s for field %s\n", fieldType.Elem(), structFieldName)
+						slog.Warn("Cannot convert map value", "field", structFieldName, "key", mapKey, "type", fieldType.Elem())
 						continue
 					}
 				}
@@ -229,26 +312,44 @@ func populateStructFromMap(dataUnknown interface{}, result interface{}) error {
 		}
 
 		// Handle conversion of other types (int, string, etc.)
-		if fieldType.Kind() == reflect.Int && reflect.TypeOf(value).Kind() == reflect.String {
-			intValue, err := strconv.Atoi(value.(string))
-			if err != nil {
-				fmt.Printf("Cannot convert string to int for field %s: %s\n", structFieldName, err)
-				continue
-			}
-			field.SetInt(int64(intValue))
+		val, err := convertScalar(value, fieldType)
+		if err != nil {
+			slog.Warn("Cannot convert field", "field", structFieldName, "err", err)
 			continue
 		}
+		field.Set(val)
+	}
+	return nil
+}
 
-		val := reflect.ValueOf(value)
-		if fieldType != val.Type() {
-			if val.Type().ConvertibleTo(fieldType) {
-				val = val.Convert(fieldType)
-			} else {
-				fmt.Printf("Cannot convert %s to %s for field %s\n", val.Type(), fieldType, structFieldName)
-				continue
+// Text vdf values are always strings, so numbers have to be parsed into the field's type
+func convertScalar(value interface{}, targetType reflect.Type) (reflect.Value, error) {
+	if stringValue, ok := value.(string); ok {
+		if targetType.Kind() >= reflect.Int && targetType.Kind() <= reflect.Int64 {
+			intValue, err := strconv.ParseInt(stringValue, 10, targetType.Bits())
+			if err != nil {
+				return reflect.Value{}, err
 			}
+			return reflect.ValueOf(intValue).Convert(targetType), nil
+		}
+		if targetType.Kind() >= reflect.Uint && targetType.Kind() <= reflect.Uint64 {
+			uintValue, err := strconv.ParseUint(stringValue, 10, targetType.Bits())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			return reflect.ValueOf(uintValue).Convert(targetType), nil
 		}
-		field.Set(val)
 	}
-	return nil
+
+	val := reflect.ValueOf(value)
+	if !val.IsValid() {
+		return reflect.Value{}, fmt.Errorf("no value for %v", targetType)
+	}
+	if val.Type() != targetType {
+		if !val.Type().ConvertibleTo(targetType) {
+			return reflect.Value{}, fmt.Errorf("can't convert %v to %v", val.Type(), targetType)
+		}
+		val = val.Convert(targetType)
+	}
+	return val, nil
 }
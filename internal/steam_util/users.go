@@ -0,0 +1,93 @@
+package steam_util
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gmod-cef-codec-fix-native/internal/steam_steamid"
+)
+
+// Not read from a vdf file directly, this is a loginusers.vdf entry along with what Steam keeps on disk for it.
+type LoginUser struct {
+	SteamUser
+	AvatarPath string
+	// Launch options live in localconfig.vdf, so an account without one has never configured anything here
+	HasLocalConfig bool
+}
+
+// ListLoginUsers returns every account that has logged in to this Steam install, best default first:
+//  1. accounts with a localconfig.vdf before ones without
+//  2. the account Steam marked MostRecent
+//  3. the newest Timestamp
+//  4. the lowest SteamID64, so the order never depends on map iteration
+func ListLoginUsers(steamPath string) ([]LoginUser, error) {
+	loginUsers, err := GetLoginUsers(steamPath)
+	if err != nil {
+		return nil, err
+	}
+	var users []LoginUser
+	for steamId64, steamUser := range loginUsers.Users {
+		steamUser.SteamID64 = steamId64
+		steamUser.AccountId = fmt.Sprintf("%v", steam_steamid.NewSteamIDFrom64(steamId64).AccountID)
+		avatarPath, _ := GetUserAvatar(steamPath, steamUser)
+		_, localConfigErr := os.Stat(localConfigPath(steamPath, steamUser))
+		users = append(users, LoginUser{
+			SteamUser:      steamUser,
+			AvatarPath:     avatarPath,
+			HasLocalConfig: localConfigErr == nil,
+		})
+	}
+	if len(users) == 0 {
+		return nil, errors.New("No Steam accounts have logged in on this computer")
+	}
+	slices.SortFunc(users, func(a, b LoginUser) int {
+		if a.HasLocalConfig != b.HasLocalConfig {
+			if a.HasLocalConfig {
+				return -1
+			}
+			return 1
+		}
+		return compareSteamUsers(a.SteamUser, b.SteamUser)
+	})
+	return users, nil
+}
+
+// Most recent login first
+func compareSteamUsers(a, b SteamUser) int {
+	return cmp.Or(
+		cmp.Compare(b.MostRecent, a.MostRecent),
+		cmp.Compare(b.Timestamp, a.Timestamp),
+		cmp.Compare(a.SteamID64, b.SteamID64),
+	)
+}
+
+// SelectLoginUser picks the account matching selector, which can be an account name, a persona name or
+// anything steam_steamid can parse. An empty selector picks the default, the first of ListLoginUsers.
+func SelectLoginUser(users []LoginUser, selector string) (*LoginUser, error) {
+	if len(users) == 0 {
+		return nil, errors.New("No Steam accounts to choose from")
+	}
+	if selector == "" {
+		return &users[0], nil
+	}
+	var accountId uint32
+	if steamId, err := steam_steamid.ParseSteamID(selector); err == nil {
+		accountId = steamId.AccountID
+	}
+	for i := range users {
+		if strings.EqualFold(users[i].AccountName, selector) || users[i].PersonaName == selector ||
+			fmt.Sprintf("%v", accountId) == users[i].AccountId {
+			return &users[i], nil
+		}
+	}
+	return nil, fmt.Errorf("No Steam account matching %v has logged in on this computer", selector)
+}
+
+func localConfigPath(steamPath string, steamUser SteamUser) string {
+	return filepath.Join(steamPath, "userdata", steamUser.AccountId, "config", "localconfig.vdf")
+}
@@ -6,9 +6,10 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"sort"
 	"strings"
-
-	"gmod-cef-codec-fix-native/internal/steam_steamid"
+	"time"
 )
 
 func GetGameBranch(manifest *VdfAppManifest) string {
@@ -18,45 +19,78 @@ func GetGameBranch(manifest *VdfAppManifest) string {
 	return "main"
 }
 
+// Steam calls the default branch "public" but we call it "main" like the patch manifest does
+func branchNameFromSteam(steamBranch string) string {
+	if steamBranch == "public" {
+		return "main"
+	}
+	return steamBranch
+}
+
+type GameBranch struct {
+	Name             string
+	Description      string
+	BuildID          uint32
+	TimeUpdated      time.Time
+	PasswordRequired bool
+}
+
+// Every branch listed in appinfo, with the main branch first and the rest sorted by name
+func GetGameBranches(appInfo *VdfAppInfo) []GameBranch {
+	var branches []GameBranch
+	for steamBranch, appBranch := range appInfo.Data.AppInfo.Depots.Branches {
+		branches = append(branches, GameBranch{
+			Name:             branchNameFromSteam(steamBranch),
+			Description:      appBranch.Description,
+			BuildID:          appBranch.BuildID,
+			TimeUpdated:      time.Unix(appBranch.TimeUpdated, 0),
+			PasswordRequired: appBranch.PwdRequired != 0,
+		})
+	}
+	sort.Slice(branches, func(i, j int) bool {
+		if branches[i].Name == "main" || branches[j].Name == "main" {
+			return branches[i].Name == "main"
+		}
+		return branches[i].Name < branches[j].Name
+	})
+	return branches
+}
+
+// Negative if the installed build is older than the branch's current build, positive if newer
+func (b GameBranch) CompareBuild(installedBuildID uint32) int {
+	if installedBuildID < b.BuildID {
+		return -1
+	} else if installedBuildID > b.BuildID {
+		return 1
+	}
+	return 0
+}
+
 func GameIsInGoodState(manifest *VdfAppManifest) bool {
-	if manifest.AppState.StateFlags != 4 || manifest.AppState.ScheduledAutoUpdate != 0 {
+	if !manifest.AppState.StateFlags.IsReady() || manifest.AppState.ScheduledAutoUpdate != 0 {
 		return false
 	}
 	return true
 }
 
+// Only looks at config.vdf, use ResolveCompatTool when the user and libraries are known
 func GameIsUsingProton(steamPath string, appId uint32) (bool, error) {
-	steamConfig, err := GetConfig(steamPath, appId)
+	tool, err := ResolveCompatTool(steamPath, nil, nil, appId, true)
 	if err != nil {
 		return false, err
 	}
-	compatToolMap, exists := steamConfig.InstallConfigStore.Software.Valve.Steam.CompatToolMapping[appId]
-	if exists {
-		if strings.Contains(strings.ToLower(compatToolMap.Name), "proton") {
-			// litter.Dump(gmodCompat)
-			return true, nil
-		}
-	}
-	// litter.Dump(steamConfig)
-	return false, nil
+	return tool.IsWindowsRuntime(), nil
 }
 
 func GetTargetPlatform(steamPath string, appId uint32) (string, error) {
-	targetPlatform := runtime.GOOS
-	if targetPlatform == "linux" {
-		usingProton, err := GameIsUsingProton(steamPath, appId)
-		if err != nil {
-			return "", err
-		}
-		if usingProton {
-			targetPlatform = "windows"
-		}
+	if runtime.GOOS != "linux" {
+		return CompatTargetPlatform(nil), nil
 	}
-	// Use matching name from python sys.platform
-	if targetPlatform == "windows" {
-		targetPlatform = "win32"
+	tool, err := ResolveCompatTool(steamPath, nil, nil, appId, true)
+	if err != nil {
+		return "", err
 	}
-	return targetPlatform, nil
+	return CompatTargetPlatform(tool), nil
 }
 
 func GetGameLaunchOptions(steamPath string, steamUser SteamUser, appId uint32) (string, error) {
@@ -73,51 +107,103 @@ func GetGameLaunchOptions(steamPath string, steamUser SteamUser, appId uint32) (
 	return "", nil
 }
 
+// The account Steam last logged in with, whether or not it has a localconfig.vdf
 func GetLastLoginUser(steamPath string) (*SteamUser, error) {
-	loginUsers, err := GetLoginUsers(steamPath)
+	users, err := ListLoginUsers(steamPath)
 	if err != nil {
 		return nil, err
 	}
+	lastUser := slices.MinFunc(users, func(a, b LoginUser) int {
+		return compareSteamUsers(a.SteamUser, b.SteamUser)
+	})
+	return &lastUser.SteamUser, nil
+}
 
-	timeStamp := 0
-	var lastSteamUser *SteamUser
-	for steamId64, steamUser := range loginUsers.Users {
-		steamId, err := steam_steamid.NewSteamID(fmt.Sprintf("%v", steamId64))
-		if err != nil {
-			return nil, err
+func FindGamePath(steamLibraries VdfLibraryFolders, steamUser SteamUser, gameDirName string) (string, error) {
+	for _, key := range sortedNumericKeys(steamLibraries.Libraryfolders) {
+		if gamePath := findGameDir(steamLibraries.Libraryfolders[key].Path, steamUser, gameDirName); gamePath != "" {
+			return gamePath, nil
 		}
-		steamUser.SteamID64 = steamId64
-		steamUser.AccountId = fmt.Sprintf("%v", steamId.AccountID)
-
-		if steamUser.MostRecent == 1 {
-			return &steamUser, nil
-		} else if steamUser.Timestamp > timeStamp {
-			lastSteamUser = &steamUser
-			timeStamp = steamUser.Timestamp
+	}
+	return "", errors.New("Couldn't get game path")
+}
+
+func findGameDir(libraryPath string, steamUser SteamUser, gameDirName string) string {
+	for _, gamePath := range []string{
+		filepath.Join(libraryPath, "steamapps", "common", gameDirName),
+		filepath.Join(libraryPath, "steamapps", steamUser.AccountName, gameDirName),
+	} {
+		if stat, err := os.Stat(gamePath); err == nil && stat.IsDir() {
+			return gamePath
 		}
 	}
-	if lastSteamUser != nil {
-		return lastSteamUser, nil
+	return ""
+}
+
+// Pick which install to use, either the one in the requested library
+// (by path or libraryfolders.vdf key) or the first one found.
+func SelectGameInstall(gameInstalls []GameInstall, library string) (*GameInstall, error) {
+	if len(gameInstalls) == 0 {
+		return nil, errors.New("No game installs to choose from")
+	}
+	if library == "" {
+		return &gameInstalls[0], nil
+	}
+	for i, gameInstall := range gameInstalls {
+		if gameInstall.LibraryKey == library || filepath.Clean(gameInstall.LibraryPath) == filepath.Clean(library) {
+			return &gameInstalls[i], nil
+		}
 	}
-	return nil, errors.New("Couldn't find last steam user")
+	return nil, fmt.Errorf("Game isn't installed in library %s", library)
 }
 
-func FindGamePath(steamLibraries VdfLibraryFolders, steamUser SteamUser, gameDirName string) (string, error) {
-	for _, steamLib := range steamLibraries.Libraryfolders {
-		for _, gamePath := range []string{
-			filepath.Join(steamLib.Path, "steamapps", "common", gameDirName),
-			filepath.Join(steamLib.Path, "steamapps", steamUser.AccountName, gameDirName),
-		} {
-			if stat, err := os.Stat(gamePath); err == nil && stat.IsDir() {
-				// TODO warn about multiple installs
-				return gamePath, nil
+// Pick the appinfo launch entry Steam would use for this platform and branch.
+// targetPlatform uses the same names as GetTargetPlatform.
+func ResolveGameLaunch(appInfo *VdfAppInfo, targetPlatform string, branch string) (*Launch, error) {
+	osName := map[string]string{
+		"win32":  "windows",
+		"darwin": "macos",
+	}[targetPlatform]
+	if osName == "" {
+		osName = targetPlatform
+	}
+
+	launchEntries := appInfo.Data.AppInfo.Config.Launch
+	var bestLaunch *Launch
+	bestScore := -1
+	for _, key := range sortedNumericKeys(launchEntries) {
+		launch := launchEntries[key]
+		if launch.Config.OsList != "" && !slices.Contains(strings.Split(launch.Config.OsList, ","), osName) {
+			continue
+		}
+		score := 0
+		if launch.Config.BetaKey != "" {
+			if !slices.Contains(strings.Fields(launch.Config.BetaKey), branch) {
+				continue
 			}
+			// Branch specific entries win over ones for every branch
+			score += 2
+		}
+		if launch.Type == "" || launch.Type == "default" {
+			score += 1
+		}
+		if score > bestScore {
+			bestLaunch = &launch
+			bestScore = score
 		}
 	}
-	return "", errors.New("Couldn't get game path")
+	if bestLaunch == nil {
+		return nil, fmt.Errorf("No launch entry for %s on branch %s", osName, branch)
+	}
+	return bestLaunch, nil
+}
+
+// Launch executables use windows path separators even for other platforms
+func (l *Launch) ExecutablePath(gamePath string) string {
+	return filepath.Join(gamePath, filepath.FromSlash(strings.ReplaceAll(l.Executable, `\`, "/")))
 }
 
-// Maybe useful to show the player's avatar in the GUI while this is running?
+// Path to the avatar Steam cached for the user, or empty if there isn't one
 func GetUserAvatar(steamPath string, steamUser SteamUser) (string, error) {
 	cachedAvatar := filepath.Join(steamPath, "config", "avatarcache", fmt.Sprintf("%v.png", steamUser.SteamID64))
 	if stat, err := os.Stat(cachedAvatar); err == nil && !stat.IsDir() {
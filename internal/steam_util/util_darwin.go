@@ -17,3 +17,14 @@ func GetSteamPath() (string, error) {
 	}
 	return steamPath, nil
 }
+
+func steamLaunchCommand(steamPath string, appId uint32) LaunchCommand {
+	return steamOpenURLCommand(steamPath, fmt.Sprintf("steam://rungameid/%v", appId))
+}
+
+func steamOpenURLCommand(steamPath string, steamURL string) LaunchCommand {
+	return LaunchCommand{
+		Path: "open",
+		Args: []string{steamURL},
+	}
+}
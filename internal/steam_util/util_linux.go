@@ -3,6 +3,7 @@ package steam_util
 import (
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 )
 
@@ -25,3 +26,27 @@ func GetSteamPath() (string, error) {
 	}
 	return "", fmt.Errorf("steam directory not found in any known locations")
 }
+
+// Prefer the steam command, but fall back to the URL handler for installs that don't put it on the PATH
+func steamLaunchCommand(steamPath string, appId uint32) LaunchCommand {
+	if steamCommand, err := exec.LookPath("steam"); err == nil {
+		return LaunchCommand{
+			Path: steamCommand,
+			Args: []string{"-applaunch", fmt.Sprintf("%v", appId)},
+		}
+	}
+	return steamOpenURLCommand(steamPath, fmt.Sprintf("steam://rungameid/%v", appId))
+}
+
+func steamOpenURLCommand(steamPath string, steamURL string) LaunchCommand {
+	if steamCommand, err := exec.LookPath("steam"); err == nil {
+		return LaunchCommand{
+			Path: steamCommand,
+			Args: []string{steamURL},
+		}
+	}
+	return LaunchCommand{
+		Path: "xdg-open",
+		Args: []string{steamURL},
+	}
+}
@@ -0,0 +1,105 @@
+package steam_util
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func testLaunch(executable string, osList string, betaKey string, launchType string) Launch {
+	launch := Launch{Executable: executable, Type: launchType}
+	launch.Config.OsList = osList
+	launch.Config.BetaKey = betaKey
+	return launch
+}
+
+func testAppInfo(launchEntries map[string]Launch) *VdfAppInfo {
+	appInfo := &VdfAppInfo{}
+	appInfo.Data.AppInfo.Config.Launch = launchEntries
+	return appInfo
+}
+
+func TestResolveGameLaunch(t *testing.T) {
+	// Roughly what GMod's appinfo has
+	gmod := testAppInfo(map[string]Launch{
+		"0":  testLaunch("hl2.exe", "windows", "", "default"),
+		"1":  testLaunch("hl2.sh", "linux", "", "default"),
+		"2":  testLaunch("hl2_osx", "macos", "", "default"),
+		"3":  testLaunch("hl2.exe", "windows", "", "option1"),
+		"4":  testLaunch(`bin\win64\gmod.exe`, "windows", "x86-64", "default"),
+		"5":  testLaunch("bin/linux64/hl2.sh", "linux", "x86-64", "default"),
+		"6":  testLaunch(`bin\win64\gmod.exe`, "windows", "x86-64", "option1"),
+		"10": testLaunch("hl2_dev.sh", "linux", "dev prerelease", ""),
+	})
+	tests := []struct {
+		name           string
+		appInfo        *VdfAppInfo
+		targetPlatform string
+		branch         string
+		want           string
+		wantErr        bool
+	}{
+		{"windows", gmod, "win32", "public", "hl2.exe", false},
+		{"linux", gmod, "linux", "public", "hl2.sh", false},
+		{"macos uses Steam's own OS name", gmod, "darwin", "public", "hl2_osx", false},
+		{"branch entry wins", gmod, "win32", "x86-64", `bin\win64\gmod.exe`, false},
+		{"branch entry on linux", gmod, "linux", "x86-64", "bin/linux64/hl2.sh", false},
+		{"one of several beta keys", gmod, "linux", "prerelease", "hl2_dev.sh", false},
+		{"branch without its own entry", gmod, "win32", "dev", "hl2.exe", false},
+		{"no entry for the platform", gmod, "freebsd", "public", "", true},
+		{"no entries", testAppInfo(nil), "linux", "public", "", true},
+		{
+			"an empty oslist means every OS",
+			testAppInfo(map[string]Launch{"0": testLaunch("hl2.exe", "windows", "", ""), "1": testLaunch("run.sh", "", "", "")}),
+			"linux", "public", "run.sh", false,
+		},
+		{
+			"entries for other branches are skipped",
+			testAppInfo(map[string]Launch{"0": testLaunch("beta.sh", "linux", "beta", "")}),
+			"linux", "public", "", true,
+		},
+		{
+			"default type beats options",
+			testAppInfo(map[string]Launch{"0": testLaunch("safe.sh", "linux", "", "option1"), "1": testLaunch("hl2.sh", "linux", "", "")}),
+			"linux", "public", "hl2.sh", false,
+		},
+		{
+			"ties go to the lowest key",
+			testAppInfo(map[string]Launch{"10": testLaunch("ten.sh", "linux", "", ""), "2": testLaunch("two.sh", "linux", "", "")}),
+			"linux", "public", "two.sh", false,
+		},
+	}
+	for _, test := range tests {
+		launch, err := ResolveGameLaunch(test.appInfo, test.targetPlatform, test.branch)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%v: ResolveGameLaunch(%v, %v) = %+v, want an error", test.name, test.targetPlatform, test.branch, launch)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: ResolveGameLaunch(%v, %v) failed: %v", test.name, test.targetPlatform, test.branch, err)
+			continue
+		}
+		if launch.Executable != test.want {
+			t.Errorf("%v: ResolveGameLaunch(%v, %v) = %v, want %v", test.name, test.targetPlatform, test.branch, launch.Executable, test.want)
+		}
+	}
+}
+
+func TestLaunchExecutablePath(t *testing.T) {
+	gamePath := filepath.Join("steamapps", "common", "GarrysMod")
+	tests := []struct {
+		executable string
+		want       string
+	}{
+		{"hl2.sh", filepath.Join(gamePath, "hl2.sh")},
+		{`bin\win64\gmod.exe`, filepath.Join(gamePath, "bin", "win64", "gmod.exe")},
+		{"bin/linux64/hl2.sh", filepath.Join(gamePath, "bin", "linux64", "hl2.sh")},
+	}
+	for _, test := range tests {
+		launch := Launch{Executable: test.executable}
+		if got := launch.ExecutablePath(gamePath); got != test.want {
+			t.Errorf("ExecutablePath(%v) = %v, want %v", test.executable, got, test.want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package steam_util
 import (
 	"fmt"
 	"golang.org/x/sys/windows/registry"
+	"path/filepath"
 	"strings"
 )
 
@@ -19,3 +20,17 @@ func GetSteamPath() (string, error) {
 	steamPath = strings.ReplaceAll(steamPath, "/", "\\")
 	return steamPath, nil
 }
+
+func steamLaunchCommand(steamPath string, appId uint32) LaunchCommand {
+	return LaunchCommand{
+		Path: filepath.Join(steamPath, "steam.exe"),
+		Args: []string{"-applaunch", fmt.Sprintf("%v", appId)},
+	}
+}
+
+func steamOpenURLCommand(steamPath string, steamURL string) LaunchCommand {
+	return LaunchCommand{
+		Path: filepath.Join(steamPath, "steam.exe"),
+		Args: []string{steamURL},
+	}
+}
@@ -3,14 +3,19 @@ package steam_util
 import (
 	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
+	"strconv"
 
 	"gmod-cef-codec-fix-native/internal/steam_appcache"
 )
 
-func GetSteamLibraries(steamPath string) (*VdfLibraryFolders, error) {
+// steamPackaging comes from GetSteamPackaging, Flatpak writes the paths it sees inside its sandbox
+// so they get translated to host paths
+func GetSteamLibraries(steamPath string, steamPackaging *SteamPackaging) (*VdfLibraryFolders, error) {
 	var steamLibraryFolders VdfLibraryFolders
 	err := initVdfStructFromFile(
 		filepath.Join(steamPath, "steamapps", "libraryfolders.vdf"),
@@ -19,6 +24,13 @@ func GetSteamLibraries(steamPath string) (*VdfLibraryFolders, error) {
 	if err != nil {
 		return nil, err
 	}
+	for key, steamLib := range steamLibraryFolders.Libraryfolders {
+		if hostPath := steamPackaging.HostPath(steamLib.Path); hostPath != steamLib.Path {
+			steamLib.SandboxPath = steamLib.Path
+			steamLib.Path = hostPath
+			steamLibraryFolders.Libraryfolders[key] = steamLib
+		}
+	}
 	// litter.Dump(steamLibraryFolders)
 	return &steamLibraryFolders, nil
 }
@@ -37,15 +49,20 @@ func GetLoginUsers(steamPath string) (*VdfLoginUsers, error) {
 }
 
 func GetGameManifest(steamLibraries *VdfLibraryFolders, appId uint32) (*VdfAppManifest, error) {
-	for key, steamLib := range steamLibraries.Libraryfolders {
+	libraryKeys, _ := steamLibraries.librariesToSearch(appId)
+	for _, key := range libraryKeys {
+		steamLib := steamLibraries.Libraryfolders[key]
+		if status, explanation := steamLib.Status(); status != LIBRARY_STATUS_OK {
+			slog.Debug("Skipping library", "app_id", appId, "library", steamLib.Path, "status", status, "explanation", explanation)
+			continue
+		}
 		var steamGameManifest VdfAppManifest
 		err := initVdfStructFromFile(
 			filepath.Join(steamLib.Path, "steamapps", fmt.Sprintf("appmanifest_%v.acf", appId)),
 			&steamGameManifest,
 		)
 		if err != nil {
-			delete(steamLibraries.Libraryfolders, key)
-			fmt.Printf("%s, skipping...\n", err)
+			slog.Debug("Skipping library", "app_id", appId, "library", steamLib.Path, "err", err)
 			continue
 		}
 		// litter.Dump(steamGameManifest)
@@ -54,14 +71,82 @@ func GetGameManifest(steamLibraries *VdfLibraryFolders, appId uint32) (*VdfAppMa
 	return nil, errors.New(fmt.Sprintf("Couldn't parse any game manifest"))
 }
 
+// Look through every library for the game instead of stopping at the first one,
+// so that multiple installs can be reported and the game path always belongs
+// to the library whose manifest we read.
+// Only the libraries that list the app in libraryfolders.vdf are searched unless none of them have it.
+// defaultInstallDir is only used when the manifest doesn't have an installdir.
+func FindGameInstalls(steamLibraries *VdfLibraryFolders, steamUser SteamUser, appId uint32, defaultInstallDir string) ([]GameInstall, error) {
+	var gameInstalls []GameInstall
+	var unavailableErr *LibraryUnavailableError
+	libraryKeys, claimingLibraries := steamLibraries.librariesToSearch(appId)
+	for i, key := range libraryKeys {
+		steamLib := steamLibraries.Libraryfolders[key]
+		if i == claimingLibraries {
+			if len(gameInstalls) > 0 {
+				break
+			}
+			if claimingLibraries > 0 {
+				slog.Debug("No library that lists the app has it, searching the others", "app_id", appId)
+			}
+		}
+		if status, explanation := steamLib.Status(); status != LIBRARY_STATUS_OK {
+			if i < claimingLibraries {
+				slog.Warn("Steam library with the app is unavailable", "app_id", appId, "library", steamLib.Path, "status", status, "explanation", explanation)
+				if unavailableErr == nil {
+					unavailableErr = &LibraryUnavailableError{Path: steamLib.Path, Status: status, Explanation: explanation}
+				}
+			} else {
+				slog.Debug("Skipping unavailable library", "library", steamLib.Path, "status", status, "explanation", explanation)
+			}
+			continue
+		}
+		manifestPath := filepath.Join(steamLib.Path, "steamapps", fmt.Sprintf("appmanifest_%v.acf", appId))
+		if _, err := os.Stat(manifestPath); err != nil {
+			continue
+		}
+		var steamGameManifest VdfAppManifest
+		err := initVdfStructFromFile(manifestPath, &steamGameManifest)
+		if err != nil {
+			slog.Warn("Couldn't read appmanifest, skipping", "app_id", appId, "path", manifestPath, "err", err)
+			continue
+		}
+		installDir := steamGameManifest.AppState.InstallDir
+		if installDir == "" {
+			installDir = defaultInstallDir
+		}
+		gamePath := findGameDir(steamLib.Path, steamUser, installDir)
+		if gamePath == "" {
+			slog.Warn("Found appmanifest but no install directory next to it, skipping", "app_id", appId, "path", manifestPath, "install_dir", installDir)
+			continue
+		}
+		gameInstalls = append(gameInstalls, GameInstall{
+			AppId:        appId,
+			LibraryKey:   key,
+			LibraryPath:  steamLib.Path,
+			ManifestPath: manifestPath,
+			InstallDir:   installDir,
+			GamePath:     gamePath,
+			Manifest:     &steamGameManifest,
+		})
+	}
+	if len(gameInstalls) == 0 {
+		if unavailableErr != nil {
+			return nil, unavailableErr
+		}
+		return nil, fmt.Errorf("Couldn't find app %v in any steam library", appId)
+	}
+	return gameInstalls, nil
+}
+
 func GetGameAppInfo(steamPath string, appId uint32) (*VdfAppInfo, error) {
 	vdfFilePath := path.Join(steamPath, "appcache", "appinfo.vdf")
 	var appInfo VdfAppInfo
 	vdfFile, err := os.Open(vdfFilePath)
-	defer vdfFile.Close()
 	if err != nil {
-		fmt.Print(err)
+		return nil, err
 	}
+	defer vdfFile.Close()
 	app, err := steam_appcache.GetGameSpecificAppInfo(vdfFile, appId)
 	if err != nil {
 		return nil, err
@@ -88,12 +173,30 @@ func GetConfig(steamPath string, appId uint32) (*VdfConfig, error) {
 
 func GetLocalConfig(steamPath string, steamUser SteamUser) (*VdfLocalConfig, error) {
 	var localAppConfig VdfLocalConfig
-	err := initVdfStructFromFile(
-		filepath.Join(steamPath, "userdata", steamUser.AccountId, "config", "localconfig.vdf"),
-		&localAppConfig,
-	)
+	err := initVdfStructFromFile(localConfigPath(steamPath, steamUser), &localAppConfig)
 	if err != nil {
 		return nil, err
 	}
 	return &localAppConfig, nil
 }
+
+// Lists in vdf files are maps keyed "0", "1", ... so sort them numerically to keep results stable.
+// Anything that isn't a number goes after the numbers in plain string order.
+func sortedNumericKeys[V any](vdfList map[string]V) []string {
+	keys := make([]string, 0, len(vdfList))
+	for key := range vdfList {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		iNum, iErr := strconv.Atoi(keys[i])
+		jNum, jErr := strconv.Atoi(keys[j])
+		if (iErr == nil) != (jErr == nil) {
+			return iErr == nil
+		}
+		if iErr == nil && iNum != jNum {
+			return iNum < jNum
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
@@ -0,0 +1,204 @@
+package steam_util
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSortedNumericKeys(t *testing.T) {
+	tests := []struct {
+		keys []string
+		want []string
+	}{
+		{nil, []string{}},
+		{[]string{"2", "10", "1", "0"}, []string{"0", "1", "2", "10"}},
+		// Numbers first, then everything else as strings
+		{[]string{"b", "10", "a", "2"}, []string{"2", "10", "a", "b"}},
+		{[]string{"contentstatsid", "1", "0"}, []string{"0", "1", "contentstatsid"}},
+		// The same number written differently still has a fixed order
+		{[]string{"01", "1", "001"}, []string{"001", "01", "1"}},
+		{[]string{"-1", "1", "x"}, []string{"-1", "1", "x"}},
+	}
+	for _, test := range tests {
+		vdfList := make(map[string]bool)
+		for _, key := range test.keys {
+			vdfList[key] = true
+		}
+		// Maps iterate in a random order, so run each case a few times
+		for range 10 {
+			if got := sortedNumericKeys(vdfList); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("sortedNumericKeys(%q) = %q, want %q", test.keys, got, test.want)
+				break
+			}
+		}
+	}
+}
+
+// How a library in a FindGameInstalls test is set up
+type testLibrary struct {
+	// Listed with the app in libraryfolders.vdf
+	claims bool
+	// Has an appmanifest and game directory for the app
+	installed bool
+	// "" for a working library, or LIBRARY_STATUS_MISSING / LIBRARY_STATUS_UNMOUNTED
+	status string
+}
+
+func writeTestLibraries(t *testing.T, libraries map[string]testLibrary) *VdfLibraryFolders {
+	t.Helper()
+	root := t.TempDir()
+	steamLibraries := &VdfLibraryFolders{Libraryfolders: make(map[string]Libraryfolder)}
+	for key, library := range libraries {
+		libraryPath := filepath.Join(root, "library"+key)
+		switch library.status {
+		case LIBRARY_STATUS_MISSING:
+		case LIBRARY_STATUS_UNMOUNTED:
+			// An empty mount point
+			if err := os.MkdirAll(libraryPath, 0o755); err != nil {
+				t.Fatal(err)
+			}
+		default:
+			if err := os.MkdirAll(filepath.Join(libraryPath, "steamapps", "common"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if library.installed {
+			manifest := "\"AppState\"\n{\n\t\"appid\"\t\t\"4000\"\n\t\"installdir\"\t\t\"GarrysMod\"\n\t\"buildid\"\t\t\"" + key + "\"\n}\n"
+			writeTestFile(t, filepath.Join(libraryPath, "steamapps", "common", "GarrysMod", "hl2.sh"), 0)
+			if err := os.WriteFile(filepath.Join(libraryPath, "steamapps", "appmanifest_4000.acf"), []byte(manifest), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		libraryFolder := Libraryfolder{Path: libraryPath, Apps: map[uint32]uint64{}}
+		if library.claims {
+			libraryFolder.Apps[4000] = 1000
+		}
+		steamLibraries.Libraryfolders[key] = libraryFolder
+	}
+	return steamLibraries
+}
+
+func TestFindGameInstalls(t *testing.T) {
+	tests := []struct {
+		name      string
+		libraries map[string]testLibrary
+		// Library keys of the installs found, in order
+		want            []string
+		wantUnavailable string
+	}{
+		{
+			name:      "claiming library",
+			libraries: map[string]testLibrary{"0": {}, "1": {claims: true, installed: true}},
+			want:      []string{"1"},
+		},
+		{
+			name:      "unclaimed installs are ignored when a claiming library has the app",
+			libraries: map[string]testLibrary{"0": {installed: true}, "1": {claims: true, installed: true}},
+			want:      []string{"1"},
+		},
+		{
+			name:      "several claiming libraries in numeric order",
+			libraries: map[string]testLibrary{"10": {claims: true, installed: true}, "2": {claims: true, installed: true}, "3": {}},
+			want:      []string{"2", "10"},
+		},
+		{
+			name:      "falls back when libraryfolders.vdf is out of date",
+			libraries: map[string]testLibrary{"0": {claims: true}, "1": {}, "2": {installed: true}},
+			want:      []string{"2"},
+		},
+		{
+			name:      "no library lists any apps",
+			libraries: map[string]testLibrary{"0": {}, "1": {installed: true}, "2": {installed: true}},
+			want:      []string{"1", "2"},
+		},
+		{
+			name:            "claiming library unmounted",
+			libraries:       map[string]testLibrary{"0": {}, "1": {claims: true, status: LIBRARY_STATUS_UNMOUNTED}},
+			wantUnavailable: LIBRARY_STATUS_UNMOUNTED,
+		},
+		{
+			name:            "claiming library deleted",
+			libraries:       map[string]testLibrary{"0": {}, "1": {claims: true, status: LIBRARY_STATUS_MISSING}},
+			wantUnavailable: LIBRARY_STATUS_MISSING,
+		},
+		{
+			name:      "claiming library unmounted but installed elsewhere",
+			libraries: map[string]testLibrary{"0": {installed: true}, "1": {claims: true, status: LIBRARY_STATUS_UNMOUNTED}},
+			want:      []string{"0"},
+		},
+		{
+			name:      "unavailable libraries that don't claim the app are skipped",
+			libraries: map[string]testLibrary{"0": {status: LIBRARY_STATUS_UNMOUNTED}, "1": {installed: true}},
+			want:      []string{"1"},
+		},
+		{
+			name:      "not installed",
+			libraries: map[string]testLibrary{"0": {}, "1": {status: LIBRARY_STATUS_MISSING}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			steamLibraries := writeTestLibraries(t, test.libraries)
+			gameInstalls, err := FindGameInstalls(steamLibraries, SteamUser{}, 4000, "GarrysMod")
+
+			var unavailableErr *LibraryUnavailableError
+			isUnavailable := errors.As(err, &unavailableErr)
+			switch {
+			case test.wantUnavailable != "":
+				if !isUnavailable || unavailableErr.Status != test.wantUnavailable {
+					t.Fatalf("FindGameInstalls() = %v, want a %v library error", err, test.wantUnavailable)
+				}
+				if unavailableErr.Path != steamLibraries.Libraryfolders["1"].Path {
+					t.Errorf("unavailable library = %v, want %v", unavailableErr.Path, steamLibraries.Libraryfolders["1"].Path)
+				}
+				return
+			case len(test.want) == 0:
+				if err == nil || isUnavailable {
+					t.Fatalf("FindGameInstalls() = %+v, %v, want a not found error", gameInstalls, err)
+				}
+				return
+			case err != nil:
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, gameInstall := range gameInstalls {
+				got = append(got, gameInstall.LibraryKey)
+				library := steamLibraries.Libraryfolders[gameInstall.LibraryKey]
+				if gameInstall.GamePath != filepath.Join(library.Path, "steamapps", "common", "GarrysMod") {
+					t.Errorf("install in library %v has game path %v", gameInstall.LibraryKey, gameInstall.GamePath)
+				}
+				// The manifest has to be the one from the same library
+				if buildId := fmt.Sprint(gameInstall.Manifest.AppState.BuildID); buildId != gameInstall.LibraryKey {
+					t.Errorf("install in library %v has the manifest from library %v", gameInstall.LibraryKey, buildId)
+				}
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("FindGameInstalls() found libraries %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFindGameInstallsDefaultInstallDir(t *testing.T) {
+	steamLibraries := writeTestLibraries(t, map[string]testLibrary{"0": {claims: true}})
+	libraryPath := steamLibraries.Libraryfolders["0"].Path
+	// Old manifests have no installdir, and some installs live in a directory named after the account
+	if err := os.WriteFile(filepath.Join(libraryPath, "steamapps", "appmanifest_4000.acf"), []byte("\"AppState\"\n{\n\t\"appid\"\t\t\"4000\"\n}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	gamePath := filepath.Join(libraryPath, "steamapps", "gabe", "garrysmod")
+	writeTestFile(t, filepath.Join(gamePath, "hl2.sh"), 0)
+
+	gameInstalls, err := FindGameInstalls(steamLibraries, SteamUser{AccountName: "gabe"}, 4000, "garrysmod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gameInstalls) != 1 || gameInstalls[0].InstallDir != "garrysmod" || gameInstalls[0].GamePath != gamePath {
+		t.Errorf("FindGameInstalls() = %+v, want the install in %v", gameInstalls, gamePath)
+	}
+}
@@ -0,0 +1,92 @@
+package ui
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// One row of the environment summary, Warning is shown next to the value when something looks off
+type EnvironmentField struct {
+	Label   string
+	Value   string
+	Warning string
+}
+
+// Header showing who and what we detected so a wrong user or install can be spotted before patching
+type EnvironmentPanel struct {
+	widget.BaseWidget
+	avatar     *canvas.Image
+	fields     *fyne.Container
+	userPicker *widget.Select
+}
+
+func NewEnvironmentPanel() *EnvironmentPanel {
+	panel := &EnvironmentPanel{
+		avatar:     canvas.NewImageFromResource(theme.AccountIcon()),
+		fields:     container.New(layout.NewFormLayout()),
+		userPicker: widget.NewSelect(nil, nil),
+	}
+	panel.userPicker.PlaceHolder = "Steam account"
+	panel.userPicker.Hide()
+	panel.avatar.FillMode = canvas.ImageFillContain
+	panel.avatar.SetMinSize(fyne.NewSize(64, 64))
+	panel.fields.Add(widget.NewLabel("Detecting Steam and GMod..."))
+	panel.ExtendBaseWidget(panel)
+	return panel
+}
+
+func (p *EnvironmentPanel) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(container.NewBorder(nil, nil, container.NewPadded(p.avatar), container.NewVBox(p.userPicker), p.fields))
+}
+
+// An empty path puts the default icon back
+func (p *EnvironmentPanel) SetAvatar(avatarPath string) {
+	if avatarPath == "" {
+		p.avatar.File = ""
+		p.avatar.Resource = theme.AccountIcon()
+	} else {
+		p.avatar.Resource = nil
+		p.avatar.File = avatarPath
+	}
+	p.avatar.Refresh()
+}
+
+func (p *EnvironmentPanel) SetFields(fields []EnvironmentField) {
+	p.fields.RemoveAll()
+	for _, field := range fields {
+		label := widget.NewLabel(field.Label)
+		label.TextStyle = fyne.TextStyle{Bold: true}
+		value := widget.NewLabel(field.Value)
+		value.Truncation = fyne.TextTruncateEllipsis
+		if field.Warning == "" {
+			p.fields.Add(label)
+			p.fields.Add(value)
+			continue
+		}
+		warning := widget.NewLabel(field.Warning)
+		warning.Importance = widget.WarningImportance
+		p.fields.Add(label)
+		p.fields.Add(container.NewBorder(nil, nil, nil, container.NewHBox(widget.NewIcon(theme.WarningIcon()), warning), value))
+	}
+	p.fields.Refresh()
+}
+
+// Lets the user switch Steam accounts, only shown when there is more than one to pick from.
+// onSelected gets the index into users and isn't called for the initial selection.
+func (p *EnvironmentPanel) SetUsers(users []string, selected int, onSelected func(int)) {
+	p.userPicker.OnChanged = nil
+	p.userPicker.Options = users
+	p.userPicker.SetSelectedIndex(selected)
+	p.userPicker.OnChanged = func(string) {
+		onSelected(p.userPicker.SelectedIndex())
+	}
+	if len(users) > 1 {
+		p.userPicker.Show()
+	} else {
+		p.userPicker.Hide()
+	}
+}
@@ -0,0 +1,173 @@
+package ui
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+
+	"gmod-cef-codec-fix-native/internal/log_util"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+var logLevelNames = []string{"Debug", "Info", "Warning", "Error"}
+var logLevels = map[string]slog.Level{
+	"Debug":   slog.LevelDebug,
+	"Info":    slog.LevelInfo,
+	"Warning": slog.LevelWarn,
+	"Error":   slog.LevelError,
+}
+
+// Shows what is in a log_util.RingBuffer, filtered by level and search text
+type LogView struct {
+	widget.BaseWidget
+	ring     *log_util.RingBuffer
+	window   fyne.Window
+	list     *widget.List
+	level    *widget.Select
+	search   *widget.Entry
+	mutex    sync.Mutex
+	filtered []string
+	// Height of one line including the separator, to tell whether the list is scrolled to the bottom
+	lineHeight float32
+}
+
+func NewLogView(ring *log_util.RingBuffer, window fyne.Window) *LogView {
+	v := &LogView{
+		ring:   ring,
+		window: window,
+		search: widget.NewEntry(),
+	}
+	v.list = widget.NewList(
+		func() int {
+			v.mutex.Lock()
+			defer v.mutex.Unlock()
+			return len(v.filtered)
+		},
+		newLogLine,
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			v.mutex.Lock()
+			line := ""
+			if id < len(v.filtered) {
+				line = v.filtered[id]
+			}
+			v.mutex.Unlock()
+			item.(*widget.Label).SetText(line)
+		},
+	)
+	v.level = widget.NewSelect(logLevelNames, func(string) { v.refilter() })
+	v.level.SetSelected("Info")
+	v.search.SetPlaceHolder("Search")
+	v.search.OnChanged = func(string) { v.refilter() }
+	ring.OnAdd(v.add)
+	v.ExtendBaseWidget(v)
+	v.lineHeight = newLogLine().MinSize().Height + v.Theme().Size(theme.SizeNamePadding)
+	v.refilter()
+	return v
+}
+
+func newLogLine() fyne.CanvasObject {
+	label := widget.NewLabel("")
+	label.TextStyle = fyne.TextStyle{Monospace: true}
+	label.Truncation = fyne.TextTruncateEllipsis
+	return label
+}
+
+func (v *LogView) CreateRenderer() fyne.WidgetRenderer {
+	toolbar := container.NewBorder(nil, nil, v.level, container.NewHBox(
+		widget.NewButtonWithIcon("Copy all", theme.ContentCopyIcon(), v.copyAll),
+		widget.NewButtonWithIcon("Save to file", theme.DocumentSaveIcon(), v.saveToFile),
+	), v.search)
+	return widget.NewSimpleRenderer(container.NewBorder(toolbar, nil, nil, nil, v.list))
+}
+
+// Start over from everything in the ring, for when the filters change
+func (v *LogView) refilter() {
+	var filtered []string
+	for _, entry := range v.ring.Entries() {
+		if line, shown := v.filter(entry); shown {
+			filtered = append(filtered, line)
+		}
+	}
+	v.mutex.Lock()
+	v.filtered = filtered
+	v.mutex.Unlock()
+	if v.list != nil {
+		v.list.Refresh()
+		v.list.ScrollToBottom()
+	}
+}
+
+// Append a new entry if it passes the filters. Only follows along if the list was already at the
+// bottom, so reading further up isn't interrupted by every new line.
+func (v *LogView) add(entry log_util.Entry) {
+	line, shown := v.filter(entry)
+	if !shown {
+		return
+	}
+	v.mutex.Lock()
+	atBottom := v.atBottom(len(v.filtered))
+	v.filtered = append(v.filtered, line)
+	// The ring drops its oldest entries, so keep no more lines than it holds
+	if excess := len(v.filtered) - v.ring.Capacity(); excess > 0 {
+		v.filtered = slices.Delete(v.filtered, 0, excess)
+	}
+	v.mutex.Unlock()
+	if atBottom {
+		v.list.ScrollToBottom()
+	} else {
+		v.list.Refresh()
+	}
+}
+
+func (v *LogView) filter(entry log_util.Entry) (string, bool) {
+	if entry.Level < logLevels[v.level.Selected] {
+		return "", false
+	}
+	line := entry.String()
+	search := strings.ToLower(v.search.Text)
+	if search != "" && !strings.Contains(strings.ToLower(line), search) {
+		return "", false
+	}
+	return line, true
+}
+
+// Whether the last of lineCount lines is in view
+func (v *LogView) atBottom(lineCount int) bool {
+	contentHeight := float32(lineCount) * v.lineHeight
+	return v.list.GetScrollOffset()+v.list.Size().Height >= contentHeight-v.lineHeight/2
+}
+
+func (v *LogView) text() string {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	return strings.Join(v.filtered, "\n") + "\n"
+}
+
+// Copies what is currently shown, so the filters apply
+func (v *LogView) copyAll() {
+	v.window.Clipboard().SetContent(v.text())
+}
+
+func (v *LogView) saveToFile() {
+	saveDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, v.window)
+			return
+		}
+		if writer == nil {
+			return
+		}
+		defer writer.Close()
+		if _, err := writer.Write([]byte(v.text())); err != nil {
+			dialog.ShowError(err, v.window)
+		}
+	}, v.window)
+	saveDialog.SetFileName("gmodcefcodecfix.log")
+	saveDialog.Show()
+}
@@ -0,0 +1,269 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// One manifest file as shown in the ResultsTable
+type FileResult struct {
+	Path     string
+	Status   string
+	Expected string
+	Actual   string
+	Size     string
+	Action   string
+	// What is currently happening to the file and how far along it is, from 0 to 1
+	Stage    string
+	Progress float64
+}
+
+const (
+	resultsColumnStatus = iota
+	resultsColumnPath
+	resultsColumnExpected
+	resultsColumnActual
+	resultsColumnSize
+	resultsColumnAction
+	resultsColumnProgress
+	resultsColumnCount
+)
+
+var resultsColumnHeaders = []string{"Status", "File", "Expected hash", "Actual hash", "Size", "Action", "Progress"}
+var resultsColumnWidths = []float32{110, 260, 110, 110, 80, 160, 140}
+
+// How many characters of a hash to show, clicking the cell copies the whole thing
+const resultsHashLength = 12
+
+// Hashing and patching report progress for every chunk, only redraw a progress cell when it moved
+// at least this much or it's been a while since the last redraw
+const (
+	resultsProgressStep     = 0.01
+	resultsProgressInterval = 100 * time.Millisecond
+)
+
+// What a progress cell was last redrawn with
+type drawnProgress struct {
+	stage    string
+	progress float64
+	at       time.Time
+}
+
+// Per file results with an overall progress bar and a summary banner once everything is done.
+// Safe to update from any goroutine.
+type ResultsTable struct {
+	widget.BaseWidget
+	mutex     sync.Mutex
+	rows      []FileResult
+	drawn     []drawnProgress
+	table     *widget.Table
+	overall   *widget.ProgressBar
+	banner    *widget.Label
+	clipboard fyne.Clipboard
+}
+
+func NewResultsTable(clipboard fyne.Clipboard) *ResultsTable {
+	t := &ResultsTable{
+		overall:   widget.NewProgressBar(),
+		banner:    widget.NewLabel(""),
+		clipboard: clipboard,
+	}
+	t.banner.Hide()
+	t.banner.Alignment = fyne.TextAlignCenter
+	t.banner.TextStyle = fyne.TextStyle{Bold: true}
+	t.table = widget.NewTableWithHeaders(t.size, t.createCell, t.updateCell)
+	t.table.UpdateHeader = func(id widget.TableCellID, template fyne.CanvasObject) {
+		label := template.(*widget.Label)
+		if id.Row == -1 && id.Col >= 0 {
+			label.SetText(resultsColumnHeaders[id.Col])
+		} else {
+			label.SetText("")
+		}
+	}
+	t.table.ShowHeaderColumn = false
+	for col, width := range resultsColumnWidths {
+		t.table.SetColumnWidth(col, width)
+	}
+	t.table.OnSelected = t.copyCell
+	t.ExtendBaseWidget(t)
+	return t
+}
+
+func (t *ResultsTable) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(container.NewBorder(
+		container.NewVBox(t.banner, t.overall),
+		nil, nil, nil,
+		t.table,
+	))
+}
+
+func (t *ResultsTable) size() (int, int) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return len(t.rows), resultsColumnCount
+}
+
+func (t *ResultsTable) createCell() fyne.CanvasObject {
+	label := widget.NewLabel("")
+	label.Truncation = fyne.TextTruncateEllipsis
+	return container.NewStack(label, widget.NewProgressBar())
+}
+
+func (t *ResultsTable) updateCell(id widget.TableCellID, cell fyne.CanvasObject) {
+	t.mutex.Lock()
+	if id.Row >= len(t.rows) {
+		t.mutex.Unlock()
+		return
+	}
+	row := t.rows[id.Row]
+	t.mutex.Unlock()
+
+	stack := cell.(*fyne.Container)
+	label := stack.Objects[0].(*widget.Label)
+	progressBar := stack.Objects[1].(*widget.ProgressBar)
+	if id.Col == resultsColumnProgress {
+		label.Hide()
+		progressBar.Show()
+		progressBar.TextFormatter = func() string {
+			return fmt.Sprintf("%s %.0f%%", row.Stage, row.Progress*100)
+		}
+		progressBar.SetValue(row.Progress)
+		return
+	}
+	progressBar.Hide()
+	label.Show()
+	switch id.Col {
+	case resultsColumnStatus:
+		label.SetText(row.Status)
+	case resultsColumnPath:
+		label.SetText(row.Path)
+	case resultsColumnExpected:
+		label.SetText(truncateHash(row.Expected))
+	case resultsColumnActual:
+		label.SetText(truncateHash(row.Actual))
+	case resultsColumnSize:
+		label.SetText(row.Size)
+	case resultsColumnAction:
+		label.SetText(row.Action)
+	}
+}
+
+func truncateHash(hash string) string {
+	if len(hash) <= resultsHashLength {
+		return hash
+	}
+	return hash[:resultsHashLength] + "…"
+}
+
+// Copy the full value of whatever cell was clicked
+func (t *ResultsTable) copyCell(id widget.TableCellID) {
+	t.table.Unselect(id)
+	t.mutex.Lock()
+	if id.Row < 0 || id.Row >= len(t.rows) {
+		t.mutex.Unlock()
+		return
+	}
+	row := t.rows[id.Row]
+	t.mutex.Unlock()
+	value := map[int]string{
+		resultsColumnPath:     row.Path,
+		resultsColumnExpected: row.Expected,
+		resultsColumnActual:   row.Actual,
+		resultsColumnAction:   row.Action,
+	}[id.Col]
+	if value != "" && t.clipboard != nil {
+		t.clipboard.SetContent(value)
+	}
+}
+
+// Start over with a new set of files
+func (t *ResultsTable) Reset(paths []string, expected []string) {
+	t.mutex.Lock()
+	t.rows = make([]FileResult, len(paths))
+	for i, path := range paths {
+		t.rows[i] = FileResult{Path: path, Expected: expected[i], Status: "waiting"}
+	}
+	t.drawn = make([]drawnProgress, len(paths))
+	t.mutex.Unlock()
+	t.overall.SetValue(0)
+	t.banner.Hide()
+	t.table.Refresh()
+}
+
+// Replace the row with the same path, keeping its place in the table
+func (t *ResultsTable) UpdateFile(result FileResult) {
+	t.mutex.Lock()
+	for i := range t.rows {
+		if t.rows[i].Path == result.Path {
+			t.rows[i] = result
+		}
+	}
+	t.mutex.Unlock()
+	t.refreshProgress()
+}
+
+// Called for every chunk that gets hashed or patched, so only the progress cell is redrawn and only
+// when the change is big enough to see
+func (t *ResultsTable) SetProgress(path string, stage string, progress float64) {
+	now := time.Now()
+	var changedRows []int
+	t.mutex.Lock()
+	for i := range t.rows {
+		if t.rows[i].Path != path {
+			continue
+		}
+		t.rows[i].Stage = stage
+		t.rows[i].Progress = progress
+		drawn := &t.drawn[i]
+		if stage == drawn.stage && progress < 1 && math.Abs(progress-drawn.progress) < resultsProgressStep &&
+			now.Sub(drawn.at) < resultsProgressInterval {
+			continue
+		}
+		*drawn = drawnProgress{stage: stage, progress: progress, at: now}
+		changedRows = append(changedRows, i)
+	}
+	t.mutex.Unlock()
+	if len(changedRows) == 0 {
+		return
+	}
+	t.refreshOverall()
+	for _, row := range changedRows {
+		t.table.RefreshItem(widget.TableCellID{Row: row, Col: resultsColumnProgress})
+	}
+}
+
+func (t *ResultsTable) refreshProgress() {
+	t.refreshOverall()
+	t.table.Refresh()
+}
+
+// Overall progress is the average of every file's progress
+func (t *ResultsTable) refreshOverall() {
+	t.mutex.Lock()
+	total := 0.0
+	for _, row := range t.rows {
+		total += row.Progress
+	}
+	if len(t.rows) > 0 {
+		total /= float64(len(t.rows))
+	}
+	t.mutex.Unlock()
+	t.overall.SetValue(total)
+}
+
+func (t *ResultsTable) SetSummary(summary string, success bool) {
+	t.banner.SetText(summary)
+	if success {
+		t.banner.Importance = widget.SuccessImportance
+	} else {
+		t.banner.Importance = widget.DangerImportance
+	}
+	t.banner.Show()
+	t.banner.Refresh()
+}
@@ -2,10 +2,22 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"path"
+	"log/slog"
+	"maps"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
 	"sync"
+	"time"
 
+	"gmod-cef-codec-fix-native/internal/log_util"
 	"gmod-cef-codec-fix-native/internal/patching_util"
 	"gmod-cef-codec-fix-native/internal/steam_util"
 	"gmod-cef-codec-fix-native/internal/ui"
@@ -16,131 +28,644 @@ import (
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
 )
 
 const (
-	GMOD_APP_ID  = 4000
+	GMOD_APP_ID = 4000
+	// How often to log download progress while waiting for Steam
+	DOWNLOAD_LOG_INTERVAL = 10 * time.Second
+	// Only used if the appmanifest doesn't say where the game is installed
 	GMOD_APP_DIR = "GarrysMod"
 )
 
-// Just compare the checksums as a demo for now
-func process() {
+var (
+	userFlag         = flag.String("user", "", "Steam account (account name, persona name or any SteamID) to use when several have logged in on this computer")
+	libraryFlag      = flag.String("library", "", "Steam library (path or libraryfolders.vdf key) to use when GMod is installed in more than one")
+	launchFlag       = flag.Bool("launch", false, "Launch GMod after a successful run")
+	launchDirectFlag = flag.Bool("launch-direct", false, "Launch the GMod executable directly instead of going through Steam")
+	clearCacheFlag   = flag.Bool("clear-cache", false, "Clear GMod's Chromium cache after a successful run, even if nothing needed patching")
+	watchFlag        = flag.Bool("watch", false, "Keep running and re-apply the fix whenever Steam updates GMod")
+	trayFlag         = flag.Bool("tray", false, "Start in the system tray and keep checking the patch status")
+	verboseFlag      = flag.Bool("verbose", false, "Show debug messages on the console")
+	quietFlag        = flag.Bool("quiet", false, "Only show warnings and errors on the console")
+	diagnosticsFlag  = flag.String("diagnostics", "", "Write a zip with logs and everything we know about the GMod install to this path for support requests")
+	noRedactFlag     = flag.Bool("no-redact", false, "Don't hide account names, SteamIDs and home directory paths in the diagnostics zip")
+	waitFlag         = flag.Bool("wait-for-download", false, "Wait for Steam to finish downloading or updating GMod instead of stopping")
+	resetPrefixFlag  = flag.Bool("reset-prefix", false, "Back up GMod's Proton prefix and let Proton create a fresh one on the next launch")
+)
+
+// Everything we need to know about the GMod install before looking at any files
+type gmodEnvironment struct {
+	SteamPath      string
+	SteamPackaging *steam_util.SteamPackaging
+	// Libraries the sandboxed Steam can't use and why, by path
+	LibraryWarnings map[string]string
+	SteamUsers      []steam_util.LoginUser
+	SteamUser       *steam_util.SteamUser
+	Installs        []steam_util.GameInstall
+	Install         *steam_util.GameInstall
+	AppInfo         *steam_util.VdfAppInfo
+	TargetPlatform  string
+	CompatTool      *steam_util.CompatTool
+	UsingProton     bool
+	// Only set when UsingProton
+	ProtonPrefix  *steam_util.ProtonPrefix
+	Branch        string
+	Launch        *steam_util.Launch
+	Executable    string
+	LaunchOptions string
+}
+
+func detectEnvironment() (*gmodEnvironment, error) {
 	steamPath, err := steam_util.GetSteamPath()
 	if err != nil {
-		fmt.Println(err)
-		return
+		return nil, err
 	}
 
-	lastSteamUser, err := steam_util.GetLastLoginUser(steamPath)
+	steamUsers, err := steam_util.ListLoginUsers(steamPath)
 	if err != nil {
-		fmt.Println(err)
-		return
+		return nil, err
 	}
-	litter.Dump(lastSteamUser)
-
-	steamLibraries, err := steam_util.GetSteamLibraries(steamPath)
+	userChoice := settings.User()
+	selectedUser, err := steam_util.SelectLoginUser(steamUsers, userChoice)
 	if err != nil {
-		fmt.Println(err)
-		return
+		return nil, err
+	}
+	if len(steamUsers) > 1 && userChoice == "" {
+		for _, steamUser := range steamUsers {
+			slog.Info("Found Steam account", "account", steamUser.AccountName, "persona", steamUser.PersonaName,
+				"steamid", steamUser.SteamID64, "has_localconfig", steamUser.HasLocalConfig)
+		}
+		slog.Info("Several Steam accounts have logged in on this computer, use -user to pick a different one",
+			"using", selectedUser.AccountName)
 	}
+	if !selectedUser.HasLocalConfig {
+		slog.Warn("The Steam account has no localconfig.vdf, launch options can't be read", "account", selectedUser.AccountName)
+	}
+	steamUser := &selectedUser.SteamUser
 
-	gmodManifest, err := steam_util.GetGameManifest(steamLibraries, GMOD_APP_ID)
+	steamPackaging, err := steam_util.GetSteamPackaging(steamPath)
 	if err != nil {
-		fmt.Println(err)
-		return
+		return nil, err
+	}
+	steamLibraries, err := steam_util.GetSteamLibraries(steamPath, steamPackaging)
+	if err != nil {
+		return nil, err
+	}
+	libraryWarnings := steamPackaging.CheckLibraries(steamLibraries)
+	if steamPackaging.IsSandboxed() {
+		slog.Info("Steam is sandboxed", "packaging", steamPackaging.Type)
+		for _, libraryPath := range slices.Sorted(maps.Keys(libraryWarnings)) {
+			slog.Warn("Steam library isn't accessible to the sandboxed Steam", "library", libraryPath, "explanation", libraryWarnings[libraryPath])
+		}
 	}
-	litter.Dump(gmodManifest)
 
-	targetPlatform, err := steam_util.GetTargetPlatform(steamPath, GMOD_APP_ID)
+	gmodInstalls, err := steam_util.FindGameInstalls(steamLibraries, *steamUser, GMOD_APP_ID, GMOD_APP_DIR)
 	if err != nil {
-		fmt.Println(err)
-		return
+		return nil, err
+	}
+	if len(gmodInstalls) > 1 {
+		for _, gmodInstall := range gmodInstalls {
+			slog.Info("Found GMod", "library", gmodInstall.LibraryKey, "path", gmodInstall.GamePath)
+		}
+		slog.Warn("GMod is installed in multiple Steam libraries, use -library to pick a different one", "count", len(gmodInstalls))
+	}
+	gmodInstall, err := steam_util.SelectGameInstall(gmodInstalls, *libraryFlag)
+	if err != nil {
+		return nil, err
+	}
+	gmodManifest := gmodInstall.Manifest
+	if gmodManifest.AppState.TargetBuildID != 0 && gmodManifest.AppState.BuildID != gmodManifest.AppState.TargetBuildID {
+		slog.Warn("Steam wants a different GMod build than the installed one, an update is probably pending",
+			"build", gmodManifest.AppState.BuildID, "target_build", gmodManifest.AppState.TargetBuildID)
 	}
-	fmt.Println(targetPlatform)
 
 	gmodAppInfo, err := steam_util.GetGameAppInfo(steamPath, GMOD_APP_ID)
 	if err != nil {
-		fmt.Println(err)
-		return
+		return nil, err
 	}
-	litter.Dump(gmodAppInfo)
 
-	gmodExeOptions, err := steam_util.GetGameLaunchOptions(steamPath, *lastSteamUser, GMOD_APP_ID)
-	if err != nil {
-		fmt.Println(err)
-		return
+	// Compat tools only exist on Linux
+	var compatTool *steam_util.CompatTool
+	if runtime.GOOS == "linux" {
+		compatTool, err = steam_util.ResolveCompatTool(steamPath, steamUser, steamLibraries, GMOD_APP_ID, steam_util.AppHasNativeLinux(gmodAppInfo))
+		if err != nil {
+			return nil, err
+		}
+		if compatTool != nil && compatTool.Runtime == "" {
+			slog.Warn("Couldn't tell whether the compat tool runs the Windows or the Linux version of GMod, assuming Linux",
+				"app_id", GMOD_APP_ID, "tool", compatTool.Name)
+		}
+	}
+	usingProton := compatTool.IsWindowsRuntime()
+	targetPlatform := steam_util.CompatTargetPlatform(compatTool)
+
+	var protonPrefix *steam_util.ProtonPrefix
+	if usingProton {
+		protonPrefix, err = steam_util.FindProtonPrefix(steamPath, steamLibraries, gmodInstall)
+		if err != nil {
+			return nil, err
+		}
+		if health, explanation := protonPrefix.Health(compatTool); health != steam_util.PREFIX_HEALTH_OK {
+			slog.Warn("Proton prefix needs attention", "path", protonPrefix.CompatDataPath, "health", health, "explanation", explanation)
+		}
+	}
+
+	gmodExeOptions := ""
+	if selectedUser.HasLocalConfig {
+		gmodExeOptions, err = steam_util.GetGameLaunchOptions(steamPath, *steamUser, GMOD_APP_ID)
+		if err != nil {
+			return nil, err
+		}
 	}
-	litter.Dump(gmodExeOptions)
 
 	gmodBranch := steam_util.GetGameBranch(gmodManifest)
 
-	manifest, err := patching_util.GetManifest(targetPlatform, gmodBranch)
+	gmodLaunch, err := steam_util.ResolveGameLaunch(gmodAppInfo, targetPlatform, gmodBranch)
 	if err != nil {
-		fmt.Println(err)
-		return
+		return nil, err
+	}
+	gmodExecutable := gmodLaunch.ExecutablePath(gmodInstall.GamePath)
+	if stat, err := os.Stat(gmodExecutable); err != nil || stat.IsDir() {
+		return nil, fmt.Errorf("GMod executable %v is missing, try verifying the game files in Steam", gmodExecutable)
 	}
 
-	gmodGamePath, err := steam_util.FindGamePath(*steamLibraries, *lastSteamUser, GMOD_APP_DIR)
-	if err != nil {
-		fmt.Println(err)
-		return
+	return &gmodEnvironment{
+		SteamPath:       steamPath,
+		SteamPackaging:  steamPackaging,
+		LibraryWarnings: libraryWarnings,
+		SteamUsers:      steamUsers,
+		SteamUser:       steamUser,
+		Installs:        gmodInstalls,
+		Install:         gmodInstall,
+		AppInfo:         gmodAppInfo,
+		TargetPlatform:  targetPlatform,
+		CompatTool:      compatTool,
+		UsingProton:     usingProton,
+		ProtonPrefix:    protonPrefix,
+		Branch:          gmodBranch,
+		Launch:          gmodLaunch,
+		Executable:      gmodExecutable,
+		LaunchOptions:   gmodExeOptions,
+	}, nil
+}
+
+// Hash every file in the manifest and patch the ones that need it if asked to.
+// hashCache can be nil to always read the files, observer can be nil if nothing is following along.
+func checkFiles(ctx context.Context, env *gmodEnvironment, manifest patching_util.BranchPatchManifest, hashCache *patching_util.HashCache, patch bool, observer fileObserver) *StatusReport {
+	if observer == nil {
+		observer = nopFileObserver{}
+	}
+	report := &StatusReport{
+		GamePath:       env.Install.GamePath,
+		Executable:     env.Executable,
+		Branch:         env.Branch,
+		TargetPlatform: env.TargetPlatform,
+		BuildID:        env.Install.Manifest.AppState.BuildID,
+		TargetBuildID:  env.Install.Manifest.AppState.TargetBuildID,
+		LastUpdated:    time.Unix(env.Install.Manifest.AppState.LastUpdated, 0),
+	}
+	if env.CompatTool != nil {
+		report.CompatTool = env.CompatTool.Name
+		report.CompatToolVersion = env.CompatTool.Version
+	}
+	if env.ProtonPrefix != nil {
+		report.ProtonPrefix = env.ProtonPrefix.CompatDataPath
+		report.ProtonPrefixHealth, _ = env.ProtonPrefix.Health(env.CompatTool)
 	}
-	fmt.Println("GAME PATH ", gmodGamePath)
 
-	gmodIsReady := steam_util.GameIsInGoodState(gmodManifest)
-	if !gmodIsReady {
-		fmt.Println("Gmod isn't ready")
-		return
+	var pendingFiles []FileStatus
+	for filePath, patchInfo := range manifest {
+		pendingFiles = append(pendingFiles, FileStatus{Path: filePath, Expected: patchInfo.Fixed})
 	}
+	sort.Slice(pendingFiles, func(i, j int) bool {
+		return pendingFiles[i].Path < pendingFiles[j].Path
+	})
+	observer.FilesStarted(pendingFiles)
 
 	var wg sync.WaitGroup
+	var reportMutex sync.Mutex
 	wg.Add(len(manifest))
 	for filePath, patchInfo := range manifest {
 		go func() {
 			defer wg.Done()
-			fileSha, err := patching_util.GetFileSHA256(path.Join(gmodGamePath, filePath))
-			if err != nil {
-				fmt.Println(err)
+			fullPath := filepath.Join(env.Install.GamePath, filepath.FromSlash(filePath))
+			fileLog := slog.With("app_id", env.Install.AppId, "file", filePath)
+			lastStage := ""
+			progress := func(stage string, done int64, total int64) {
+				if stage != lastStage {
+					fileLog.Debug("Stage started", "stage", stage, "total", total)
+					lastStage = stage
+				}
+				observer.FileProgress(filePath, stage, done, total)
+			}
+			var fileSha string
+			var err error
+			if hashCache != nil {
+				fileSha, err = hashCache.GetFileSHA256(fullPath, progress)
+			} else {
+				fileSha, err = patching_util.GetFileSHA256WithProgress(fullPath, progress)
 			}
-			if fileSha == patchInfo.Fixed {
-				fmt.Println(fmt.Sprintf("✅ %v", filePath))
+
+			fileStatus := FileStatus{
+				Path:     filePath,
+				Status:   FILE_STATUS_NEEDS_PATCH,
+				Expected: patchInfo.Fixed,
+				Actual:   fileSha,
+				Action:   "none",
+			}
+			if stat, err := os.Stat(fullPath); err == nil {
+				fileStatus.Size = stat.Size()
+			}
+			if err != nil {
+				fileLog.Error("Couldn't hash file", "stage", lastStage, "err", err)
+				fileStatus.Status = FILE_STATUS_UNREADABLE
+				fileStatus.Action = err.Error()
+			} else if fileSha == patchInfo.Fixed {
+				fileLog.Info("✅ File is patched")
+				fileStatus.Status = FILE_STATUS_OK
+			} else if patch {
+				if err := patching_util.PatchFile(ctx, fullPath, patchInfo, progress); err != nil {
+					fileLog.Error("❌ Couldn't patch file", "stage", lastStage, "err", err)
+					fileStatus.Status = FILE_STATUS_PATCH_FAILED
+					fileStatus.Action = err.Error()
+				} else {
+					fileLog.Info("🔧 Patched file")
+					fileStatus.Status = FILE_STATUS_PATCHED
+					fileStatus.Action = "patched"
+					fileStatus.Actual = patchInfo.Fixed
+				}
 			} else {
-				fmt.Println(fmt.Sprintf("❌ %v", filePath))
+				fileLog.Warn("❌ File needs patching")
+				fileStatus.Action = "not patched"
 			}
+			observer.FileFinished(fileStatus)
+			reportMutex.Lock()
+			report.AddFile(fileStatus)
+			reportMutex.Unlock()
 		}()
 	}
 	wg.Wait()
+	observer.Finished(report)
+	return report
+}
+
+// Waiting for Steam stops early once ctx is cancelled
+func process(ctx context.Context, launchAfter bool, observer fileObserver) {
+	env, err := detectEnvironment()
+	if err != nil {
+		slog.Error("Couldn't detect GMod", "err", err)
+		return
+	}
+	slog.Debug("Steam user", "user", litter.Sdump(env.SteamUser))
+	slog.Debug("GMod appmanifest", "manifest", litter.Sdump(env.Install.Manifest))
+	slog.Debug("GMod appinfo", "appinfo", litter.Sdump(env.AppInfo))
+	slog.Info("Detected GMod", "app_id", env.Install.AppId, "path", env.Install.GamePath, "executable", env.Executable,
+		"platform", env.TargetPlatform, "compat_tool", env.CompatTool, "branch", env.Branch, "launch_options", env.LaunchOptions)
+
+	cacheDir, err := patching_util.GetCacheDir()
+	if err != nil {
+		slog.Error("Couldn't create the cache directory", "err", err)
+		return
+	}
+	// Always try for a fresh manifest here, the cached one is only a fallback
+	patchManifest, err := patching_util.LoadManifest(ctx, cacheDir, 0)
+	if err != nil {
+		slog.Error("Couldn't get the patch manifest", "err", err)
+		return
+	}
+	logBranches(steam_util.GetGameBranches(env.AppInfo), patchManifest.Branches(env.TargetPlatform), env.Branch, env.Install.Manifest.AppState.BuildID)
+	manifest, err := patchManifest.GetBranch(env.TargetPlatform, env.Branch)
+	if err != nil {
+		slog.Error("Nothing to patch", "err", err)
+		return
+	}
+
+	gmodManifest := env.Install.Manifest
+	download := env.Install.DownloadProgress()
+	if !steam_util.GameIsInGoodState(gmodManifest) || download.Active() {
+		slog.Warn("GMod isn't ready", "state", gmodManifest.AppState.StateFlags)
+		for _, explanation := range steam_util.ExplainGameState(gmodManifest) {
+			slog.Warn("GMod state", "explanation", explanation)
+		}
+		if download.InProgress() {
+			slog.Warn("Steam is updating GMod", "progress", describeDownload(download))
+		}
+		if !gmodManifest.AppState.StateFlags.InProgress() && !download.Active() {
+			return
+		}
+		if !settings.WaitForDownload() {
+			slog.Warn("Not checking the files while Steam is busy, try again when it's done or use -wait-for-download")
+			return
+		}
+		slog.Info("Waiting for Steam to finish...")
+		var lastLogged time.Time
+		err := env.Install.WaitUntilReady(ctx, func(download *steam_util.DownloadProgress) {
+			if time.Since(lastLogged) >= DOWNLOAD_LOG_INTERVAL {
+				slog.Info("Waiting for Steam", "progress", describeDownload(download))
+				lastLogged = time.Now()
+			}
+		})
+		if errors.Is(err, context.Canceled) {
+			slog.Info("Stopped waiting for Steam")
+			return
+		}
+		if err != nil {
+			slog.Error("Gave up waiting for Steam", "err", err)
+			return
+		}
+	}
+
+	report := checkFiles(ctx, env, manifest, nil, true, observer)
+	report.Log()
+
+	if report.AllFilesOK() && (report.AnyFilesPatched() || *clearCacheFlag) {
+		clearCEFCache(env)
+	}
+
+	if !launchAfter {
+		return
+	}
+	if !report.AllFilesOK() {
+		slog.Warn("Not launching GMod because some files aren't patched")
+		return
+	}
+	launchGame(steam_util.ExecLauncher{}, env)
+}
+
+func clearCEFCache(env *gmodEnvironment) {
+	protonPrefix := ""
+	if env.ProtonPrefix != nil {
+		protonPrefix = env.ProtonPrefix.PrefixPath()
+	}
+	cacheDirs, err := patching_util.FindCEFCacheDirs(env.Install.GamePath, protonPrefix)
+	if err != nil {
+		slog.Error("Couldn't find the Chromium cache", "err", err)
+		return
+	}
+	if len(cacheDirs) == 0 {
+		slog.Info("No Chromium cache to clear")
+		return
+	}
+	for _, cacheDir := range cacheDirs {
+		slog.Info("Clearing Chromium cache", "path", cacheDir.Path, "size", formatBytes(cacheDir.Size))
+	}
+	if err := patching_util.RemoveCEFCacheDirs(cacheDirs); err != nil {
+		slog.Error("Couldn't clear the Chromium cache", "err", err)
+	}
+}
+
+// Move the prefix aside so Proton starts over, for when GMod won't start or the prefix is from a newer Proton
+func resetProtonPrefix(env *gmodEnvironment) error {
+	if env.ProtonPrefix == nil {
+		return fmt.Errorf("GMod doesn't run through Proton, there's no prefix to reset")
+	}
+	if env.Install.Manifest.AppState.StateFlags.Has(steam_util.AppStateAppRunning) {
+		return fmt.Errorf("GMod is running, close it before resetting the prefix")
+	}
+	backupPath, err := env.ProtonPrefix.BackupAndReset()
+	if err != nil {
+		return err
+	}
+	slog.Info("Reset the Proton prefix, Proton creates a new one on the next launch", "path", env.ProtonPrefix.CompatDataPath, "backup", backupPath)
+	return nil
+}
+
+func launchGame(launcher steam_util.Launcher, env *gmodEnvironment) {
+	launchDirect := *launchDirectFlag
+	if launchDirect && env.TargetPlatform == "win32" && runtime.GOOS != "windows" {
+		slog.Warn("GMod needs Proton so it can't be launched directly, going through Steam instead")
+		launchDirect = false
+	}
+	var err error
+	if launchDirect {
+		slog.Info("Launching GMod", "executable", env.Executable, "arguments", env.Launch.Arguments, "launch_options", env.LaunchOptions)
+		err = steam_util.LaunchGameDirectly(launcher, env.Executable, env.Launch.Arguments, env.LaunchOptions)
+	} else {
+		slog.Info("Launching GMod through Steam")
+		err = steam_util.LaunchGameViaSteam(launcher, env.SteamPath, GMOD_APP_ID)
+	}
+	if err != nil {
+		slog.Error("Couldn't launch GMod", "err", err)
+	}
+}
+
+// Show which branches exist, which ones we can patch and how the installed build compares
+func logBranches(branches []steam_util.GameBranch, patchableBranches []string, currentBranch string, installedBuildID uint32) {
+	for _, branch := range branches {
+		slog.Info("GMod branch", "branch", branch.Name, "build", branch.BuildID,
+			"updated", branch.TimeUpdated.Format(time.DateOnly),
+			"patch_available", slices.Contains(patchableBranches, branch.Name),
+			"installed", branch.Name == currentBranch)
+		if branch.Name == currentBranch {
+			switch branch.CompareBuild(installedBuildID) {
+			case -1:
+				slog.Warn("Installed build is older than the current build for this branch", "build", installedBuildID)
+			case 1:
+				slog.Warn("Installed build is newer than the current build for this branch", "build", installedBuildID)
+			}
+		}
+	}
+}
+
+// How many log entries the GUI keeps around
+const LOG_BUFFER_SIZE = 5000
+
+// Log to the console, a log file that can be attached to bug reports and the GUI log view.
+// -verbose and -quiet only change the console, the file and the GUI always get everything.
+func setupLogging() *log_util.RingBuffer {
+	consoleLevel := slog.LevelInfo
+	if *verboseFlag {
+		consoleLevel = slog.LevelDebug
+	} else if *quietFlag {
+		consoleLevel = slog.LevelWarn
+	}
+	logRing := log_util.NewRingBuffer(LOG_BUFFER_SIZE)
+	handlers := []slog.Handler{
+		slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: consoleLevel}),
+		log_util.NewRingHandler(logRing, slog.LevelDebug),
+	}
+	logFile, logFileErr := openLogFile()
+	if logFileErr == nil {
+		handlers = append(handlers, slog.NewTextHandler(logFile, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+	slog.SetDefault(slog.New(log_util.NewFanoutHandler(handlers...)))
+	if logFileErr != nil {
+		slog.Warn("Couldn't open the log file, only logging to the console", "err", logFileErr)
+	} else {
+		slog.Debug("Logging to file", "path", logFile.Path(), "args", os.Args[1:])
+	}
+	return logRing
+}
+
+func openLogFile() (*log_util.RotatingFile, error) {
+	logDir, err := log_util.GetLogDir()
+	if err != nil {
+		return nil, err
+	}
+	return log_util.OpenRotatingFile(filepath.Join(logDir, "GModCEFCodecFix.log"), log_util.LOG_FILE_MAX_SIZE, log_util.LOG_FILE_BACKUPS)
 }
 
 func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [game command line]\n\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "Set GMod's launch options to \"<path to this tool> %%command%%\" to check the files on every launch.\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	settings.loadFlags()
+
+	ui.AttachToConsole()
+	logRing := setupLogging()
+
+	// Anything left over is the game's command line from `gmodcefcodecfix %command%`
+	if flag.NArg() > 0 {
+		runWrapper(flag.Args())
+		return
+	}
+
+	if *diagnosticsFlag != "" {
+		if err := saveDiagnostics(*diagnosticsFlag, logRing, !*noRedactFlag); err != nil {
+			slog.Error("Couldn't save diagnostics", "err", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *resetPrefixFlag {
+		env, err := detectEnvironment()
+		if err == nil {
+			err = resetProtonPrefix(env)
+		}
+		if err != nil {
+			slog.Error("Couldn't reset the Proton prefix", "err", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *watchFlag {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if err := runWatch(ctx); err != nil {
+			slog.Error("Watch mode stopped", "err", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	mainApp := app.New()
 	mainWindow := mainApp.NewWindow("GmodCEFCodecFix-native demo")
 
 	bgImage := canvas.NewImageFromReader(bytes.NewReader(ui.BgImgData), "bgImage")
 	bgImage.FillMode = canvas.ImageFillContain
 
-	textBox := ui.NewTransparentEntry()
-	textBox.SetText("GmodCEFCodecFix-native demo\n(it only compares the checksums as a proof of concept)\n\n")
-	textBox.Wrapping = fyne.TextWrapWord
-	textBox.MultiLine = true
+	logView := ui.NewLogView(logRing, mainWindow)
+
+	environmentPanel := ui.NewEnvironmentPanel()
+	go refreshEnvironmentPanel(environmentPanel)
+
+	resultsTable := ui.NewResultsTable(mainWindow.Clipboard())
+	resultsObserver := &resultsTableObserver{table: resultsTable}
 
-	var launchButton *widget.Button
-	launchButton = widget.NewButton("Patch", func() {
+	// Cancelled when the app quits, closing the window does that unless the tray keeps it running
+	appCtx, stopApp := context.WithCancel(context.Background())
+	defer stopApp()
+	mainApp.Lifecycle().SetOnStopped(stopApp)
+
+	var patchButton, launchButton, cancelButton *widget.Button
+	// Only the wait for Steam can be cancelled, patching is quick and shouldn't stop halfway through a file
+	startRun := func(launchAfter bool) {
+		patchButton.Disable()
 		launchButton.Disable()
-		go process()
+		runCtx, cancelRun := context.WithCancel(appCtx)
+		cancelButton.OnTapped = cancelRun
+		cancelButton.Enable()
+		go func() {
+			defer func() {
+				cancelRun()
+				cancelButton.Disable()
+				patchButton.Enable()
+				launchButton.Enable()
+			}()
+			process(runCtx, launchAfter, resultsObserver)
+			refreshEnvironmentPanel(environmentPanel)
+		}()
+	}
+	patchButton = widget.NewButton("Patch", func() { startRun(*launchFlag) })
+	patchButton.Importance = widget.HighImportance
+	launchButton = widget.NewButton("Patch & Launch", func() { startRun(true) })
+	cancelButton = widget.NewButton("Cancel", nil)
+	cancelButton.Disable()
+	waitCheck := widget.NewCheck("Wait for Steam to finish updating GMod", settings.SetWaitForDownload)
+	waitCheck.SetChecked(settings.WaitForDownload())
+	clearCacheButton := widget.NewButton("Clear Chromium cache", func() {
+		go func() {
+			env, err := detectEnvironment()
+			if err != nil {
+				slog.Error("Couldn't detect GMod", "err", err)
+				return
+			}
+			clearCEFCache(env)
+		}()
 	})
-	launchButton.Importance = widget.HighImportance
-
-	ui.AttachToConsole()
-	ui.InterceptTextOutputToGui(textBox)
+	diagnosticsButton := widget.NewButton("Save diagnostics", func() {
+		saveDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil {
+				slog.Error("Couldn't save diagnostics", "err", err)
+				return
+			}
+			if writer == nil {
+				return
+			}
+			go func() {
+				err := writeDiagnostics(writer, logRing, !*noRedactFlag)
+				if closeErr := writer.Close(); err == nil {
+					err = closeErr
+				}
+				if err != nil {
+					slog.Error("Couldn't save diagnostics", "err", err)
+					return
+				}
+				slog.Info("Saved diagnostics, attach this file to your support request", "path", writer.URI().Path(), "redacted", !*noRedactFlag)
+			}()
+		}, mainWindow)
+		saveDialog.SetFileName(diagnosticsFileName())
+		saveDialog.Show()
+	})
+	buttons := []fyne.CanvasObject{patchButton, launchButton, cancelButton, clearCacheButton, diagnosticsButton}
+	// Proton prefixes only exist on Linux
+	if runtime.GOOS == "linux" {
+		resetPrefixButton := widget.NewButton("Reset Proton prefix", func() {
+			dialog.ShowConfirm("Reset Proton prefix",
+				"GMod's Proton prefix will be moved to a backup and Proton will create a fresh one on the next launch.\nContinue?",
+				func(confirmed bool) {
+					if !confirmed {
+						return
+					}
+					go func() {
+						env, err := detectEnvironment()
+						if err == nil {
+							err = resetProtonPrefix(env)
+						}
+						if err != nil {
+							slog.Error("Couldn't reset the Proton prefix", "err", err)
+						}
+						refreshEnvironmentPanel(environmentPanel)
+					}()
+				}, mainWindow)
+		})
+		buttons = append(buttons, resetPrefixButton)
+	}
 
 	mainWindowContent := container.NewBorder(
 		// Top
-		nil,
+		environmentPanel,
 
 		// Bottom
-		launchButton,
+		container.NewVBox(waitCheck, container.NewGridWithColumns(len(buttons), buttons...)),
 
 		// Left
 		nil,
@@ -149,14 +674,24 @@ func main() {
 		nil,
 
 		// Center
-		container.NewStack(
-			container.New(&ui.BottomRightLayout{},
-				bgImage,
+		container.NewVSplit(
+			resultsTable,
+			container.NewStack(
+				container.New(&ui.BottomRightLayout{},
+					bgImage,
+				),
+				logView,
 			),
-			textBox,
 		),
 	)
 	mainWindow.SetContent(mainWindowContent)
 	mainWindow.Resize(fyne.NewSize(900, 600))
+	if *trayFlag {
+		if setupTray(appCtx, mainApp, mainWindow) {
+			mainApp.Run()
+			return
+		}
+		slog.Warn("No system tray available, opening the window instead")
+	}
 	mainWindow.ShowAndRun()
 }
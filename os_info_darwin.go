@@ -0,0 +1,14 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+)
+
+func osDescription() string {
+	version, err := exec.Command("sw_vers", "-productVersion").Output()
+	if err != nil {
+		return "unknown macOS"
+	}
+	return "macOS " + strings.TrimSpace(string(version))
+}
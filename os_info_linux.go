@@ -0,0 +1,22 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// Distro name from os-release, which every distro Steam supports has
+func osDescription() string {
+	for _, osReleasePath := range []string{"/etc/os-release", "/usr/lib/os-release"} {
+		osRelease, err := os.ReadFile(osReleasePath)
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(osRelease), "\n") {
+			if value, found := strings.CutPrefix(line, "PRETTY_NAME="); found {
+				return strings.Trim(value, `"'`)
+			}
+		}
+	}
+	return "unknown Linux"
+}
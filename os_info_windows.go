@@ -0,0 +1,20 @@
+package main
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// Something like "Windows 10 Pro 22H2 (build 19045)"
+func osDescription() string {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, `SOFTWARE\Microsoft\Windows NT\CurrentVersion`, registry.QUERY_VALUE)
+	if err != nil {
+		return "unknown Windows"
+	}
+	defer key.Close()
+	productName, _, _ := key.GetStringValue("ProductName")
+	displayVersion, _, _ := key.GetStringValue("DisplayVersion")
+	currentBuild, _, _ := key.GetStringValue("CurrentBuild")
+	return fmt.Sprintf("%v %v (build %v)", productName, displayVersion, currentBuild)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"gmod-cef-codec-fix-native/internal/steam_util"
+)
+
+// Summary of a run so support can tell exactly which install and build was checked
+type StatusReport struct {
+	GamePath       string
+	Executable     string
+	Branch         string
+	TargetPlatform string
+	// Empty when the game runs natively
+	CompatTool        string
+	CompatToolVersion string
+	// Empty unless the game runs through Proton
+	ProtonPrefix       string
+	ProtonPrefixHealth string
+	BuildID            uint32
+	TargetBuildID      uint32
+	LastUpdated        time.Time
+	Files              []FileStatus
+}
+
+type FileStatus struct {
+	Path     string
+	Status   string
+	Expected string
+	Actual   string
+	Size     int64
+	Action   string
+}
+
+const (
+	FILE_STATUS_OK           = "ok"
+	FILE_STATUS_PATCHED      = "patched"
+	FILE_STATUS_NEEDS_PATCH  = "needs patch"
+	FILE_STATUS_PATCH_FAILED = "patch failed"
+	FILE_STATUS_UNREADABLE   = "unreadable"
+)
+
+func (r *StatusReport) AddFile(file FileStatus) {
+	r.Files = append(r.Files, file)
+	sort.Slice(r.Files, func(i, j int) bool {
+		return r.Files[i].Path < r.Files[j].Path
+	})
+}
+
+func (r *StatusReport) AllFilesOK() bool {
+	for _, file := range r.Files {
+		if file.Status != FILE_STATUS_OK && file.Status != FILE_STATUS_PATCHED {
+			return false
+		}
+	}
+	return len(r.Files) > 0
+}
+
+func (r *StatusReport) AnyFilesPatched() bool {
+	for _, file := range r.Files {
+		if file.Status == FILE_STATUS_PATCHED {
+			return true
+		}
+	}
+	return false
+}
+
+// Log the report one record per line, so the GUI's log view and the log file don't get one giant message
+func (r *StatusReport) Log() {
+	attrs := []any{"game_path", r.GamePath, "executable", r.Executable, "branch", r.Branch, "target_platform", r.TargetPlatform}
+	if r.CompatTool != "" {
+		attrs = append(attrs, "compat_tool", fmt.Sprintf("%v %v", r.CompatTool, r.CompatToolVersion))
+	}
+	if r.ProtonPrefix != "" {
+		attrs = append(attrs, "proton_prefix", r.ProtonPrefix, "proton_prefix_health", r.ProtonPrefixHealth)
+	}
+	attrs = append(attrs, "build_id", r.BuildID)
+	if r.TargetBuildID != 0 && r.TargetBuildID != r.BuildID {
+		attrs = append(attrs, "target_build_id", r.TargetBuildID)
+	}
+	attrs = append(attrs, "last_updated", r.LastUpdated.Format(time.DateTime))
+	slog.Info("Status report", attrs...)
+	for _, file := range r.Files {
+		slog.Info("File status", "status", file.Status, "path", file.Path)
+	}
+}
+
+// Steam's byte counts if it has them, otherwise what's in the download directories so far
+func describeDownload(download *steam_util.DownloadProgress) string {
+	var parts []string
+	if download.BytesToDownload > 0 {
+		parts = append(parts, fmt.Sprintf("downloaded %v of %v (%.0f%%)", formatBytes(int64(download.BytesDownloaded)),
+			formatBytes(int64(download.BytesToDownload)), download.DownloadFraction()*100))
+	}
+	if download.BytesToStage > 0 {
+		parts = append(parts, fmt.Sprintf("staged %v of %v (%.0f%%)", formatBytes(int64(download.BytesStaged)),
+			formatBytes(int64(download.BytesToStage)), download.StageFraction()*100))
+	}
+	if len(parts) == 0 && download.DownloadingPath != "" {
+		parts = append(parts, fmt.Sprintf("%v downloaded", formatBytes(download.DownloadingSize())))
+	}
+	if len(parts) == 0 && download.TempPath != "" {
+		parts = append(parts, fmt.Sprintf("%v in temp", formatBytes(download.TempSize())))
+	}
+	if len(parts) == 0 {
+		return "no progress yet"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,44 @@
+package main
+
+import "sync"
+
+// Choices the GUI can change while detection and patching run on other goroutines.
+// They start out as whatever was given on the command line.
+type appSettings struct {
+	mutex           sync.Mutex
+	user            string
+	waitForDownload bool
+}
+
+var settings appSettings
+
+func (s *appSettings) loadFlags() {
+	s.SetUser(*userFlag)
+	s.SetWaitForDownload(*waitFlag)
+}
+
+// Steam account to use, empty to pick the most recent login
+func (s *appSettings) User() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.user
+}
+
+func (s *appSettings) SetUser(user string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.user = user
+}
+
+// Whether a run waits for Steam to finish updating GMod instead of stopping
+func (s *appSettings) WaitForDownload() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.waitForDownload
+}
+
+func (s *appSettings) SetWaitForDownload(wait bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.waitForDownload = wait
+}
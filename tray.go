@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"gmod-cef-codec-fix-native/internal/patching_util"
+	"gmod-cef-codec-fix-native/internal/steam_util"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/theme"
+)
+
+// How often the tray re-checks the files on its own, the hash cache keeps this cheap
+const TRAY_CHECK_INTERVAL = 15 * time.Minute
+
+type trayState int
+
+const (
+	TRAY_STATE_CHECKING trayState = iota
+	TRAY_STATE_PATCHED
+	TRAY_STATE_NEEDS_PATCH
+	TRAY_STATE_ERROR
+)
+
+func (s trayState) icon() fyne.Resource {
+	switch s {
+	case TRAY_STATE_PATCHED:
+		return theme.ConfirmIcon()
+	case TRAY_STATE_NEEDS_PATCH:
+		return theme.WarningIcon()
+	case TRAY_STATE_ERROR:
+		return theme.ErrorIcon()
+	}
+	return theme.ViewRefreshIcon()
+}
+
+type trayCompanion struct {
+	// Cancelled when the app quits
+	ctx        context.Context
+	app        desktop.App
+	window     fyne.Window
+	menu       *fyne.Menu
+	statusItem *fyne.MenuItem
+	hashCache  *patching_util.HashCache
+	cacheDir   string
+	// Only one check/patch/restore at a time
+	busy sync.Mutex
+}
+
+// Put the app in the system tray and hide the window there instead of exiting when it is closed.
+// Returns false if the platform has no system tray.
+func setupTray(ctx context.Context, mainApp fyne.App, mainWindow fyne.Window) bool {
+	desk, ok := mainApp.(desktop.App)
+	if !ok {
+		return false
+	}
+	cacheDir, err := patching_util.GetCacheDir()
+	if err != nil {
+		slog.Error("Couldn't find the cache directory", "err", err)
+		return false
+	}
+
+	tray := &trayCompanion{
+		ctx:        ctx,
+		app:        desk,
+		window:     mainWindow,
+		statusItem: fyne.NewMenuItem("", nil),
+		hashCache:  patching_util.LoadHashCache(cacheDir),
+		cacheDir:   cacheDir,
+	}
+	tray.statusItem.Disabled = true
+	quitItem := fyne.NewMenuItem("Quit", mainApp.Quit)
+	quitItem.IsQuit = true
+	tray.menu = fyne.NewMenu("GModCEFCodecFix",
+		tray.statusItem,
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("Check now", func() { go tray.runExclusive(tray.check) }),
+		fyne.NewMenuItem("Patch", func() { go tray.runExclusive(tray.patch) }),
+		fyne.NewMenuItem("Restore", func() { go tray.runExclusive(tray.restore) }),
+		fyne.NewMenuItem("Open log", tray.showWindow),
+		fyne.NewMenuItemSeparator(),
+		quitItem,
+	)
+	desk.SetSystemTrayMenu(tray.menu)
+	tray.setState(TRAY_STATE_CHECKING, "Checking...")
+
+	mainWindow.SetCloseIntercept(mainWindow.Hide)
+	go tray.checkPeriodically()
+	return true
+}
+
+func (t *trayCompanion) setState(state trayState, status string) {
+	t.app.SetSystemTrayIcon(state.icon())
+	t.statusItem.Label = status
+	t.menu.Refresh()
+}
+
+func (t *trayCompanion) showWindow() {
+	t.window.Show()
+	t.window.RequestFocus()
+}
+
+func (t *trayCompanion) runExclusive(action func()) {
+	if !t.busy.TryLock() {
+		return
+	}
+	defer t.busy.Unlock()
+	action()
+}
+
+func (t *trayCompanion) checkPeriodically() {
+	ticker := time.NewTicker(TRAY_CHECK_INTERVAL)
+	defer ticker.Stop()
+	for {
+		t.runExclusive(t.check)
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Same as a normal run except nothing gets patched
+func (t *trayCompanion) check() {
+	t.setState(TRAY_STATE_CHECKING, "Checking...")
+	env, err := detectEnvironment()
+	if err != nil {
+		slog.Error("Tray check failed", "err", err)
+		t.setState(TRAY_STATE_ERROR, "Error: "+err.Error())
+		return
+	}
+	patchManifest, err := patching_util.LoadManifest(t.ctx, t.cacheDir, WATCH_MANIFEST_MAX_AGE)
+	if err != nil {
+		slog.Error("Tray check failed", "err", err)
+		t.setState(TRAY_STATE_ERROR, "Error: "+err.Error())
+		return
+	}
+	manifest, err := patchManifest.GetBranch(env.TargetPlatform, env.Branch)
+	if err != nil {
+		slog.Error("Tray check failed", "err", err)
+		t.setState(TRAY_STATE_ERROR, "Error: "+err.Error())
+		return
+	}
+	if download := env.Install.DownloadProgress(); download.Active() {
+		t.setState(TRAY_STATE_CHECKING, fmt.Sprintf("Waiting for Steam (%v)", describeDownload(download)))
+		return
+	}
+	if !steam_util.GameIsInGoodState(env.Install.Manifest) {
+		t.setState(TRAY_STATE_CHECKING, fmt.Sprintf("Waiting for Steam (%v)", env.Install.Manifest.AppState.StateFlags))
+		return
+	}
+
+	report := checkFiles(t.ctx, env, manifest, t.hashCache, false, nil)
+	if err := t.hashCache.Save(); err != nil {
+		slog.Warn("Couldn't save the hash cache", "err", err)
+	}
+	if report.AllFilesOK() {
+		t.setState(TRAY_STATE_PATCHED, fmt.Sprintf("Patched (build %v)", report.BuildID))
+	} else {
+		t.setState(TRAY_STATE_NEEDS_PATCH, fmt.Sprintf("Needs patching (build %v)", report.BuildID))
+	}
+}
+
+func (t *trayCompanion) patch() {
+	t.setState(TRAY_STATE_CHECKING, "Patching...")
+	process(t.ctx, false, nil)
+	t.check()
+}
+
+func (t *trayCompanion) restore() {
+	env, err := detectEnvironment()
+	if err != nil {
+		slog.Error("Tray check failed", "err", err)
+		t.setState(TRAY_STATE_ERROR, "Error: "+err.Error())
+		return
+	}
+	slog.Info("Asking Steam to verify GMod's files, this puts the original files back")
+	if err := steam_util.ValidateGameViaSteam(steam_util.ExecLauncher{}, env.SteamPath, GMOD_APP_ID); err != nil {
+		slog.Error("Tray check failed", "err", err)
+		t.setState(TRAY_STATE_ERROR, "Error: "+err.Error())
+		return
+	}
+	t.setState(TRAY_STATE_CHECKING, "Waiting for Steam to restore the original files")
+}
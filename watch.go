@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"gmod-cef-codec-fix-native/internal/patching_util"
+	"gmod-cef-codec-fix-native/internal/steam_util"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	// Steam touches the appmanifest and game files many times during one update,
+	// so wait for things to go quiet before doing anything
+	WATCH_DEBOUNCE = 10 * time.Second
+	// Watch mode can run for days so don't keep using the same manifest forever
+	WATCH_MANIFEST_MAX_AGE = time.Hour
+	// Our own patching shows up as events too, ignore them for a bit after each cycle
+	WATCH_IGNORE_OWN_EVENTS = 2 * time.Second
+	// If GMod or the manifest couldn't be found there may be no event to wait for, so try again after this
+	WATCH_RETRY_INTERVAL = 5 * time.Minute
+)
+
+// Keep running and re-apply the fix whenever Steam updates GMod and reverts the files
+func runWatch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	cacheDir, err := patching_util.GetCacheDir()
+	if err != nil {
+		return err
+	}
+	hashCache := patching_util.LoadHashCache(cacheDir)
+
+	// Paths we care about, everything else in the watched directories is ignored
+	watchedFiles := make(map[string]bool)
+	retry := time.NewTimer(WATCH_RETRY_INTERVAL)
+	retry.Stop()
+	cycle := func() {
+		env, manifest, err := watchCycle(ctx, cacheDir, hashCache)
+		if err != nil {
+			slog.Error("Watch cycle failed", "err", err)
+		}
+		if env == nil || manifest == nil {
+			slog.Info("Trying again later", "in", WATCH_RETRY_INTERVAL)
+			retry.Reset(WATCH_RETRY_INTERVAL)
+		} else {
+			retry.Stop()
+		}
+		if env == nil {
+			return
+		}
+		// Steam replaces files rather than writing to them, so watch the directories they are in.
+		// This is redone every cycle in case the branch and therefore the file list changed.
+		watchPaths := []string{env.Install.ManifestPath}
+		for filePath := range manifest {
+			watchPaths = append(watchPaths, filepath.Join(env.Install.GamePath, filepath.FromSlash(filePath)))
+		}
+		for _, watchPath := range watchPaths {
+			watchedFiles[watchPath] = true
+			if err := watcher.Add(filepath.Dir(watchPath)); err != nil {
+				slog.Warn("Couldn't watch directory", "path", filepath.Dir(watchPath), "err", err)
+			}
+		}
+	}
+
+	slog.Info("Watching for GMod updates")
+	cycle()
+	ignoreEventsUntil := time.Now().Add(WATCH_IGNORE_OWN_EVENTS)
+	debounce := time.NewTimer(WATCH_DEBOUNCE)
+	debounce.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if watchedFiles[event.Name] && time.Now().After(ignoreEventsUntil) {
+				debounce.Reset(WATCH_DEBOUNCE)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			slog.Error("Watcher error", "err", err)
+		case <-debounce.C:
+			cycle()
+			ignoreEventsUntil = time.Now().Add(WATCH_IGNORE_OWN_EVENTS)
+		case <-retry.C:
+			cycle()
+			ignoreEventsUntil = time.Now().Add(WATCH_IGNORE_OWN_EVENTS)
+		}
+	}
+}
+
+// One check and patch run. Returns the environment and file list even if patching failed so they can be watched.
+func watchCycle(ctx context.Context, cacheDir string, hashCache *patching_util.HashCache) (*gmodEnvironment, patching_util.BranchPatchManifest, error) {
+	env, err := detectEnvironment()
+	if err != nil {
+		return nil, nil, err
+	}
+	patchManifest, err := patching_util.LoadManifest(ctx, cacheDir, WATCH_MANIFEST_MAX_AGE)
+	if err != nil {
+		return env, nil, err
+	}
+	manifest, err := patchManifest.GetBranch(env.TargetPlatform, env.Branch)
+	if err != nil {
+		return env, nil, err
+	}
+
+	stateFlags := env.Install.Manifest.AppState.StateFlags
+	download := env.Install.DownloadProgress()
+	if !steam_util.GameIsInGoodState(env.Install.Manifest) || download.Active() {
+		// Steam writes the appmanifest again when it finishes, which starts the next cycle
+		slog.Info("GMod isn't ready, waiting for Steam", "state", stateFlags, "progress", describeDownload(download))
+		return env, manifest, nil
+	}
+
+	slog.Info("Checking GMod", "build", env.Install.Manifest.AppState.BuildID)
+	report := checkFiles(ctx, env, manifest, hashCache, true, nil)
+	if err := hashCache.Save(); err != nil {
+		slog.Warn("Couldn't save the hash cache", "err", err)
+	}
+	if !report.AllFilesOK() {
+		return env, manifest, fmt.Errorf("Some files couldn't be patched")
+	}
+	if report.AnyFilesPatched() {
+		clearCEFCache(env)
+		slog.Info("Re-applied the fix")
+	} else {
+		slog.Info("All files are already patched")
+	}
+	return env, manifest, nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"gmod-cef-codec-fix-native/internal/patching_util"
+	"gmod-cef-codec-fix-native/internal/steam_util"
+)
+
+// The wrapper runs on every launch so don't hit the network for the manifest more than this
+const WRAPPER_MANIFEST_MAX_AGE = 24 * time.Hour
+
+// With GMod's launch options set to `gmodcefcodecfix %command%` Steam starts us with the game's
+// command line as our arguments, so check the files and then hand over to the game.
+func runWrapper(gameCommand []string) {
+	if err := wrapperCheck(); err != nil {
+		// Never stop the game from starting because of us, just complain
+		slog.Error("GModCEFCodecFix check failed, starting the game anyway", "err", err)
+	}
+	os.Exit(execGame(gameCommand))
+}
+
+func wrapperCheck() error {
+	env, err := detectEnvironment()
+	if err != nil {
+		return err
+	}
+	// Steam may already consider the game running by the time we get here
+	stateFlags := env.Install.Manifest.AppState.StateFlags &^ steam_util.AppStateAppRunning
+	if !stateFlags.IsReady() {
+		return fmt.Errorf("GMod isn't ready (%v), skipping the check", env.Install.Manifest.AppState.StateFlags)
+	}
+	if download := env.Install.DownloadProgress(); download.Active() {
+		return fmt.Errorf("Steam is still updating GMod (%v), skipping the check", describeDownload(download))
+	}
+
+	cacheDir, err := patching_util.GetCacheDir()
+	if err != nil {
+		return err
+	}
+	patchManifest, err := patching_util.LoadManifest(context.Background(), cacheDir, WRAPPER_MANIFEST_MAX_AGE)
+	if err != nil {
+		return err
+	}
+	manifest, err := patchManifest.GetBranch(env.TargetPlatform, env.Branch)
+	if err != nil {
+		return err
+	}
+
+	hashCache := patching_util.LoadHashCache(cacheDir)
+	report := checkFiles(context.Background(), env, manifest, hashCache, true, nil)
+	if err := hashCache.Save(); err != nil {
+		slog.Warn("Couldn't save the hash cache", "err", err)
+	}
+	if !report.AllFilesOK() {
+		return errors.New("some files couldn't be patched, videos probably won't work")
+	}
+	if report.AnyFilesPatched() {
+		clearCEFCache(env)
+	}
+	return nil
+}